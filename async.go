@@ -0,0 +1,117 @@
+package prettyZap
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	AsyncDropAndWarn = iota // 队列满时丢弃并计数，每 10 秒最多向 stderr 警告一次（默认）
+	AsyncDrop               // 队列满时丢弃并计数，不警告
+	AsyncBlock              // 队列满时阻塞调用方，不丢日志
+)
+
+const (
+	asyncWarnInterval = 10 * time.Second
+	asyncSyncTimeout  = 5 * time.Second
+)
+
+var errAsyncSyncTimeout = errors.New("timed out waiting for async log queue to flush")
+
+var (
+	asyncDropped  uint64
+	asyncLastWarn int64 // 上次警告的 UnixNano
+)
+
+// AsyncDropped 返回异步模式下因队列满而丢弃的日志条数
+func AsyncDropped() uint64 {
+	return atomic.LoadUint64(&asyncDropped)
+}
+
+// asyncSyncer 在调用方编码完成后把数据放入有界队列，由单独的 goroutine 按顺序写出，
+// 写文件、控制台的耗时不再计入调用方
+type asyncSyncer struct {
+	ws     zapcore.WriteSyncer
+	policy int
+	queue  chan asyncEntry
+}
+
+// asyncEntry 是队列中的一条日志，done 非 nil 时是 Sync 放入的标记，写出 goroutine 处理到它时关闭 done
+type asyncEntry struct {
+	b    []byte
+	done chan struct{}
+}
+
+func newAsyncSyncer(ws zapcore.WriteSyncer, size, policy int) *asyncSyncer {
+	s := &asyncSyncer{ws: ws, policy: policy, queue: make(chan asyncEntry, size)}
+	go s.run()
+	return s
+}
+
+func (s *asyncSyncer) Write(p []byte) (int, error) {
+	// zap 会复用 p 的底层缓冲，入队前必须拷贝
+	b := make([]byte, len(p))
+	copy(b, p)
+	if s.policy == AsyncBlock {
+		s.queue <- asyncEntry{b: b}
+		return len(p), nil
+	}
+	select {
+	case s.queue <- asyncEntry{b: b}:
+	default:
+		dropped := atomic.AddUint64(&asyncDropped, 1)
+		if s.policy == AsyncDropAndWarn {
+			now := time.Now().UnixNano()
+			last := atomic.LoadInt64(&asyncLastWarn)
+			if now-last >= int64(asyncWarnInterval) && atomic.CompareAndSwapInt64(&asyncLastWarn, last, now) {
+				warnf("async log queue is full, %d entries dropped so far", dropped)
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (s *asyncSyncer) run() {
+	for e := range s.queue {
+		if e.done != nil {
+			close(e.done)
+			continue
+		}
+		s.ws.Write(e.b)
+	}
+}
+
+// Sync 在队列末尾放入标记，等待此前入队的日志写出后再 Sync 内层输出，保证 Fatal、Shutdown 前的日志不丢。
+// 之后并发写入的日志不需要等待，持续写入时也能返回；输出阻塞时最多等待 asyncSyncTimeout
+func (s *asyncSyncer) Sync() error {
+	timer := time.NewTimer(asyncSyncTimeout)
+	defer timer.Stop()
+	done := make(chan struct{})
+	select {
+	case s.queue <- asyncEntry{done: done}:
+	case <-timer.C:
+		return errAsyncSyncTimeout
+	}
+	select {
+	case <-done:
+	case <-timer.C:
+		return errAsyncSyncTimeout
+	}
+	return s.ws.Sync()
+}
+
+// asyncOutputs 将控制台和文件输出改为异步，远程输出本身已是异步的，保持不变
+func asyncOutputs(list []zapcore.WriteSyncer, size, policy int) []zapcore.WriteSyncer {
+	out := make([]zapcore.WriteSyncer, len(list))
+	for i, ws := range list {
+		if _, ok := ws.(*remoteSyncer); ok {
+			out[i] = ws
+			continue
+		}
+		out[i] = newAsyncSyncer(ws, size, policy)
+	}
+	return out
+}
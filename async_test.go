@@ -0,0 +1,78 @@
+package prettyZap
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncSyncUnderLoad(t *testing.T) {
+	out := &lockedBuffer{}
+	s := newAsyncSyncer(out, 16, AsyncBlock)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					s.Write([]byte("noise\n"))
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	s.Write([]byte("before sync\n"))
+	done := make(chan error, 1)
+	go func() { done <- s.Sync() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Sync: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Sync did not return under concurrent logging")
+	}
+	if !strings.Contains(out.String(), "before sync\n") {
+		t.Error("entry written before Sync was not flushed")
+	}
+}
+
+func TestAsyncDropCountsFullQueue(t *testing.T) {
+	block := make(chan struct{})
+	s := newAsyncSyncer(blockingWriter{block}, 1, AsyncDrop)
+	defer close(block)
+
+	before := AsyncDropped()
+	for i := 0; i < 10; i++ {
+		s.Write([]byte("x\n"))
+	}
+	// 一条被写出 goroutine 取走阻塞，一条在队列中，其余丢弃
+	if dropped := AsyncDropped() - before; dropped < 8 {
+		t.Errorf("dropped %d entries, want at least 8", dropped)
+	}
+}
+
+type blockingWriter struct {
+	block chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.block
+	return len(p), nil
+}
+
+func (w blockingWriter) Sync() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package prettyZap
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// auditCore 是审计日志的 core，未配置 AuditFilePath 时为 nil
+var auditCore zapcore.Core
+
+// fsyncFile 每次写入后立即 fsync，写入返回时数据已落盘
+type fsyncFile struct {
+	*os.File
+}
+
+func (f fsyncFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.File.Sync()
+}
+
+// newAuditCore 以追加模式打开审计文件，不轮转、不采样、不缓冲。
+// 审计文件固定为 JSON 格式，脱敏规则（MaskPatterns、RedactKeys）与主日志相同
+func newAuditCore(cfg *PreSetConfig) (zapcore.Core, error) {
+	if err := claimFile(cfg.AuditFilePath); err != nil {
+		return nil, err
+	}
+	f, err := openWithRetry(cfg.AuditFilePath, cfg.OpenRetries)
+	if err != nil {
+		releaseFile(cfg.AuditFilePath)
+		return nil, err
+	}
+	registerCloser(cfg.AuditFilePath, f)
+	auditCfg := *cfg
+	auditCfg.Encoding = EncodingJSON
+	ws := maskOutput(zapcore.Lock(fsyncFile{f}), compileMasks(cfg.MaskPatterns))
+	core := zapcore.NewCore(newEncoder(&auditCfg), ws, zapcore.DebugLevel)
+	if len(cfg.RedactKeys) > 0 {
+		core = &rewriteCore{Core: core, field: redactFields(cfg.RedactKeys)}
+	}
+	return core.With(globalFields(cfg)), nil
+}
+
+// Audit 将一条审计日志同步写入审计文件，返回 nil 时日志已经 fsync 落盘。
+// 审计日志只写入 AuditFilePath，不受日志级别和采样影响
+func Audit(msg string, fields ...zap.Field) error {
+	if auditCore == nil {
+		return errors.New("audit log is not configured")
+	}
+	ent := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Now(),
+		Message: msg,
+		Caller:  zapcore.NewEntryCaller(runtime.Caller(1)),
+	}
+	return auditCore.Write(ent, fields)
+}
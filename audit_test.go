@@ -0,0 +1,46 @@
+package prettyZap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestAuditJSONAndRedacted(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.AuditFilePath = filepath.Join(t.TempDir(), "audit.log")
+	cfg.Encoding = EncodingCSV
+	cfg.RedactKeys = []string{"token"}
+	cfg.MaskPatterns = []string{`\d{11}`}
+	core, err := newAuditCore(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseFile(cfg.AuditFilePath)
+	old := auditCore
+	auditCore = core
+	defer func() { auditCore = old }()
+
+	if err := Audit("login 13800138000", zap.String("token", "secret"), zap.String("user", "bob")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(cfg.AuditFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 主日志为 CSV 时审计文件仍为 JSON
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("audit line is not JSON: %q", data)
+	}
+	if m["token"] != "***" || m["user"] != "bob" {
+		t.Errorf("RedactKeys not applied to audit log: %q", data)
+	}
+	if strings.Contains(string(data), "13800138000") {
+		t.Errorf("MaskPatterns not applied to audit log: %q", data)
+	}
+}
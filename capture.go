@@ -0,0 +1,44 @@
+package prettyZap
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry 是 CaptureDuring 收集到的一条结构化日志
+type Entry = observer.LoggedEntry
+
+var captureSeq uint64
+
+// CaptureDuring 在执行 f 期间额外收集写出的日志并返回，日志仍照常输出。收集的是经过 RedactKeys、
+// MaskPatterns 等处理后的日志。收集不区分 goroutine，f 执行期间其他 goroutine 的日志也会被收集；
+// 可以并发调用，每次调用只收集自己执行期间的日志。未初始化时只执行 f，返回 nil
+func CaptureDuring(f func()) []Entry {
+	if sinks == nil {
+		f()
+		return nil
+	}
+	// 作为独立命名的输出加入 sinkSet，不替换全局 logger
+	obs, logs := observer.New(zapcore.DebugLevel)
+	name := fmt.Sprintf("prettyZap.capture.%d", atomic.AddUint64(&captureSeq, 1))
+	sinks.add(name, obs)
+	defer sinks.remove(name)
+	f()
+	return logs.All()
+}
+
+// WithWriter 返回同时写入 w 的 logger，日志照常输出到各输出，同时按相同格式写一份到 w，
+// 如把某次操作的日志实时返回给调试接口的 HTTP 响应。w 只对返回的 logger 及其派生的 logger 生效，
+// 级别跟随全局级别，MaskPatterns、RedactKeys 等字段处理同样生效
+func WithWriter(w io.Writer) *zap.SugaredLogger {
+	ws := maskOutput(zapcore.Lock(zapcore.AddSync(w)), compileMasks(DefaultCfg.MaskPatterns))
+	extra := zapcore.NewCore(newEncoder(&DefaultCfg), ws, zapcore.DebugLevel)
+	// w 与各输出并列放在字段处理之下，写到 w 的内容与写到文件的一致
+	root := wrapRootCore(zapcore.NewTee(&sinkCore{set: sinks}, extra), &DefaultCfg)
+	return newLogger(root, atomicLevel, &DefaultCfg).WithOptions(zap.AddCallerSkip(-1)).Sugar()
+}
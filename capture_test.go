@@ -0,0 +1,69 @@
+package prettyZap
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestWithWriterAppliesRedaction(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.RedactKeys = []string{"token"}
+	out := captureLogger(t, cfg)
+
+	var w bytes.Buffer
+	WithWriter(&w).Infow("login", "token", "hunter2")
+
+	for name, got := range map[string]string{"sink": out.String(), "writer": w.String()} {
+		if strings.Contains(got, "hunter2") {
+			t.Errorf("%s output leaks the token: %s", name, got)
+		}
+		if !strings.Contains(got, `"serviceName":`) {
+			t.Errorf("%s output is missing global fields: %s", name, got)
+		}
+	}
+}
+
+func TestCaptureDuringConcurrent(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.RedactKeys = []string{"token"}
+	captureLogger(t, cfg)
+	prev := plainLogger
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("req-%d", i)
+			logs := CaptureDuring(func() {
+				InfoFields("handled", zap.String("request_id", id), zap.String("token", "hunter2"))
+			})
+			found := false
+			for _, e := range logs {
+				m := e.ContextMap()
+				if m["token"] == "hunter2" {
+					t.Errorf("captured entry leaks the token: %v", m)
+				}
+				found = found || m["request_id"] == id
+			}
+			if !found {
+				t.Errorf("%s: own entry not captured", id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if plainLogger != prev {
+		t.Error("global logger was replaced")
+	}
+	sinks.mu.RLock()
+	defer sinks.mu.RUnlock()
+	if len(sinks.extra) != 0 {
+		t.Errorf("%d capture sinks left installed", len(sinks.extra))
+	}
+}
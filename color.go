@@ -0,0 +1,90 @@
+package prettyZap
+
+import (
+	"os"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultLevelColors 与 zap 的 CapitalColorLevelEncoder 配色一致
+var defaultLevelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "35", // magenta
+	zapcore.InfoLevel:   "34", // blue
+	zapcore.WarnLevel:   "33", // yellow
+	zapcore.ErrorLevel:  "31", // red
+	zapcore.DPanicLevel: "31",
+	zapcore.PanicLevel:  "31",
+	zapcore.FatalLevel:  "31",
+}
+
+var colorCodes = map[string]string{
+	"black":     "30",
+	"red":       "31",
+	"green":     "32",
+	"yellow":    "33",
+	"blue":      "34",
+	"magenta":   "35",
+	"cyan":      "36",
+	"white":     "37",
+	"bold":      "1",
+	"underline": "4",
+}
+
+// levelColors 在默认配色上应用 LevelColors。颜色为空格分隔的颜色名，如 "red bold"，
+// 也可以直接写 ANSI 代码如 "1;31"；"none" 表示该级别不着色。无法识别的级别和颜色会被忽略
+func levelColors(custom map[string]string) map[zapcore.Level]string {
+	colors := make(map[zapcore.Level]string, len(defaultLevelColors))
+	for l, c := range defaultLevelColors {
+		colors[l] = c
+	}
+	for name, spec := range custom {
+		l, err := ParseLevel(name)
+		if err != nil {
+			warnf("unknown level %q in LevelColors", name)
+			continue
+		}
+		code, ok := parseColor(spec)
+		if !ok {
+			warnf("unknown color %q for level %s in LevelColors", spec, name)
+			continue
+		}
+		colors[l] = code
+	}
+	return colors
+}
+
+func parseColor(spec string) (string, bool) {
+	var codes []string
+	for _, word := range strings.Fields(strings.ToLower(spec)) {
+		if word == "none" {
+			return "", true
+		}
+		if code, ok := colorCodes[word]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		if strings.Trim(word, "0123456789;") != "" {
+			return "", false
+		}
+		codes = append(codes, word)
+	}
+	return strings.Join(codes, ";"), len(codes) > 0
+}
+
+// colorLevelEncoder 用 ANSI 颜色包裹 base 编码出的级别
+func colorLevelEncoder(base zapcore.LevelEncoder, colors map[zapcore.Level]string) zapcore.LevelEncoder {
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		s := encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { base(l, enc) })
+		if code := colors[l]; code != "" {
+			s = "\x1b[" + code + "m" + s + "\x1b[0m"
+		}
+		enc.AppendString(s)
+	}
+}
+
+// isTerminal 判断 f 是否为终端。管道、重定向到文件时返回 false，这时不输出颜色代码
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
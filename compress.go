@@ -0,0 +1,81 @@
+package prettyZap
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	compressBackoff    = time.Second
+	compressMaxBackoff = time.Minute
+)
+
+// compressBackups 返回轮转后压缩备份文件的回调，取代 lumberjack 自带的压缩：
+// lumberjack 在后台压缩并丢弃错误，磁盘写满时未压缩的备份会悄悄留下。
+// 失败时调用 onError（为 nil 时输出到 stderr），最多重试 retries 次；完成后以最终的文件名调用 next
+func compressBackups(retries int, onError func(string, error), next func(string)) func(string) {
+	if onError == nil {
+		onError = func(name string, err error) {
+			warnf("compress rotated log file %s failed: %v", name, err)
+		}
+	}
+	return func(name string) {
+		backoff := compressBackoff
+		for i := 0; ; i++ {
+			err := gzipFile(name, name+".gz")
+			if err == nil {
+				name += ".gz"
+				break
+			}
+			onError(name, err)
+			if i >= retries {
+				break
+			}
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > compressMaxBackoff {
+				backoff = compressMaxBackoff
+			}
+		}
+		if next != nil {
+			next(name)
+		}
+	}
+}
+
+// gzipFile 将 src 压缩为 dst 后删除 src，失败时删除不完整的 dst，保留 src
+func gzipFile(src, dst string) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(dst)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, f); err != nil {
+		return fmt.Errorf("write %s: %v", dst, err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("write %s: %v", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("write %s: %v", dst, err)
+	}
+	f.Close()
+	return os.Remove(src)
+}
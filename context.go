@@ -0,0 +1,118 @@
+package prettyZap
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type loggerKey struct{}
+
+// NewContext 返回携带 logger 的 context，logger 通常已通过 With 绑定了请求相关字段
+func NewContext(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext 取出 NewContext 存入的 logger，不存在时返回全局 logger。
+// 配置了 TraceFromContext 时附带 ctx 中的追踪 ID；ctx 带有 deadline 时，
+// 每条日志附带写出时距 deadline 的剩余毫秒数 deadline_remaining_ms，已超时为负数
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	logger, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger)
+	if !ok {
+		// 全局 logger 为包级函数设置了 caller skip，直接使用时需要抵消
+		logger = plainLogger.WithOptions(zap.AddCallerSkip(-1)).Sugar()
+	}
+	if DefaultCfg.TraceFromContext != nil {
+		if traceID, spanID := DefaultCfg.TraceFromContext(ctx); traceID != "" {
+			logger = logger.Desugar().With(traceFields(&DefaultCfg, traceID, spanID)...).Sugar()
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// 剩余时间在 root core 内换算，钩子和采样仍按原 logger 的 Check 链执行
+		logger = logger.With(deadlineField(deadline))
+	}
+	return logger
+}
+
+// deadlineMarker 携带 ctx 的 deadline，FromContext 以 SkipType 字段传入，由 deadlineCore 取出。
+// 不经过 root core 的 logger（如 zaptest）会忽略该字段
+type deadlineMarker time.Time
+
+func deadlineField(deadline time.Time) zap.Field {
+	return zap.Field{Type: zapcore.SkipType, Interface: deadlineMarker(deadline)}
+}
+
+// deadlineCore 从 With 的字段中取出 deadlineMarker，写出时追加距 deadline 的剩余毫秒数，已超时为负数
+type deadlineCore struct {
+	zapcore.Core
+	deadline time.Time
+}
+
+func (c *deadlineCore) With(fields []zapcore.Field) zapcore.Core {
+	deadline := c.deadline
+	kept := fields
+	for i, f := range fields {
+		if m, ok := f.Interface.(deadlineMarker); ok && f.Type == zapcore.SkipType {
+			deadline = time.Time(m)
+			kept = append(fields[:i:i], fields[i+1:]...)
+			break
+		}
+	}
+	return &deadlineCore{Core: c.Core.With(kept), deadline: deadline}
+}
+
+func (c *deadlineCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *deadlineCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.deadline.IsZero() {
+		return c.Core.Write(ent, fields)
+	}
+	remaining := zap.Int64("deadline_remaining_ms", int64(c.deadline.Sub(ent.Time)/time.Millisecond))
+	return c.Core.Write(ent, append(fields[:len(fields):len(fields)], remaining))
+}
+
+// Scope 记录一组请求相关的字段，是不可变的值，可以安全地传给其他 goroutine，
+// 在那里用 Logger 重建带有相同字段的 logger
+type Scope struct {
+	fields []zap.Field
+}
+
+type scopeKey struct{}
+
+// NewScope 返回绑定 fields 的 Scope
+func NewScope(fields ...zap.Field) Scope {
+	return Scope{}.With(fields...)
+}
+
+// With 返回追加了 fields 的新 Scope，原 Scope 不变
+func (s Scope) With(fields ...zap.Field) Scope {
+	return Scope{fields: append(s.fields[:len(s.fields):len(s.fields)], fields...)}
+}
+
+// Fields 返回 Scope 绑定的字段
+func (s Scope) Fields() []zap.Field {
+	return s.fields[:len(s.fields):len(s.fields)]
+}
+
+// Logger 基于当前的全局 logger 构建带有 Scope 字段的 logger，级别随全局级别变化
+func (s Scope) Logger() *zap.SugaredLogger {
+	return plainLogger.WithOptions(zap.AddCallerSkip(-1)).With(s.fields...).Sugar()
+}
+
+// Context 返回同时携带 Scope 和对应 logger 的 context，FromContext、ScopeFromContext 都可以取出
+func (s Scope) Context(ctx context.Context) context.Context {
+	return NewContext(context.WithValue(ctx, scopeKey{}, s), s.Logger())
+}
+
+// ScopeFromContext 取出 Scope.Context 存入的 Scope，不存在时返回空 Scope
+func ScopeFromContext(ctx context.Context) Scope {
+	s, _ := ctx.Value(scopeKey{}).(Scope)
+	return s
+}
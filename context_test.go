@@ -0,0 +1,47 @@
+package prettyZap
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFromContextDeadlineWithHooks(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.OnFatalHook = func(zapcore.Entry) {}
+	cfg.SummaryOnShutdown = true
+	out := captureLogger(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	log := FromContext(ctx)
+	for i := 0; i < 3; i++ {
+		log.Info("request")
+	}
+	// 存入 context 的 logger 同样附带剩余时间
+	FromContext(NewContext(ctx, log.With("user", "u1"))).Info("stored")
+
+	lines := out.Lines()
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), out)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"deadline_remaining_ms":`) {
+			t.Errorf("missing deadline_remaining_ms: %s", line)
+		}
+	}
+	if !strings.Contains(lines[3], `"user":"u1"`) {
+		t.Errorf("stored logger lost its fields: %s", lines[3])
+	}
+}
+
+func TestFromContextWithoutDeadline(t *testing.T) {
+	out := captureLogger(t, DefaultCfg)
+	FromContext(context.Background()).Info("request")
+	if line := out.String(); strings.Contains(line, "deadline_remaining_ms") {
+		t.Errorf("unexpected deadline_remaining_ms: %s", line)
+	}
+}
@@ -0,0 +1,273 @@
+package prettyZap
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var logSeq uint64
+
+// stampCore 为写出的每条日志追加一个计算得到的字段
+type stampCore struct {
+	zapcore.Core
+	stamp func(zapcore.Entry) zapcore.Field
+}
+
+func (c *stampCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stampCore{Core: c.Core.With(fields), stamp: c.stamp}
+}
+
+func (c *stampCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *stampCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// 三下标切片强制 append 重新分配，避免改写调用方的 fields
+	return c.Core.Write(ent, append(fields[:len(fields):len(fields)], c.stamp(ent)))
+}
+
+// nextSeq 返回进程内单调递增的日志序号
+func nextSeq(zapcore.Entry) zapcore.Field {
+	return zap.Uint64("seq", atomic.AddUint64(&logSeq, 1))
+}
+
+// goroutineID 从当前 goroutine 的栈信息 "goroutine 123 [running]:" 中解析 ID，开销较大，仅用于调试
+func goroutineID(zapcore.Entry) zapcore.Field {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return zap.Uint64("goid", id)
+}
+
+// epochMillis 以 key 输出日志时间的毫秒时间戳，与编码器写出的 time 为同一时刻
+func epochMillis(key string) func(zapcore.Entry) zapcore.Field {
+	return func(ent zapcore.Entry) zapcore.Field {
+		return zap.Int64(key, ent.Time.UnixNano()/int64(time.Millisecond))
+	}
+}
+
+// levelCore 用可动态调整的级别过滤内层 core
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func newLevelCore(core zapcore.Core, level zapcore.LevelEnabler) zapcore.Core {
+	return &levelCore{Core: core, level: level}
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
+
+// Write 同样检查级别，外层包装 core 直接调用 Write 时也不会越级写出
+func (c *levelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// schemaCore 保证 keys 中的每个 key 都出现在输出中，缺失时补空字符串
+type schemaCore struct {
+	zapcore.Core
+	keys    []string
+	entry   map[string]func(zapcore.Entry) bool // 由编码器根据 Entry 写出的 key 是否会出现
+	present map[string]struct{}                 // 通过 With 添加的 key
+}
+
+func newSchemaCore(core zapcore.Core, encCfg zapcore.EncoderConfig, keys []string) zapcore.Core {
+	always := func(zapcore.Entry) bool { return true }
+	entry := map[string]func(zapcore.Entry) bool{
+		encCfg.TimeKey:       always,
+		encCfg.LevelKey:      always,
+		encCfg.MessageKey:    always,
+		encCfg.NameKey:       func(ent zapcore.Entry) bool { return ent.LoggerName != "" },
+		encCfg.CallerKey:     func(ent zapcore.Entry) bool { return ent.Caller.Defined },
+		encCfg.FunctionKey:   func(ent zapcore.Entry) bool { return ent.Caller.Defined },
+		encCfg.StacktraceKey: func(ent zapcore.Entry) bool { return ent.Stack != "" },
+	}
+	// 未启用的 key 编码器不会写出，需要按普通字段补齐
+	delete(entry, "")
+	return &schemaCore{Core: core, keys: keys, entry: entry, present: map[string]struct{}{}}
+}
+
+func (c *schemaCore) With(fields []zapcore.Field) zapcore.Core {
+	present := make(map[string]struct{}, len(c.present)+len(fields))
+	for k := range c.present {
+		present[k] = struct{}{}
+	}
+	for _, f := range fields {
+		present[f.Key] = struct{}{}
+	}
+	return &schemaCore{Core: c.Core.With(fields), keys: c.keys, entry: c.entry, present: present}
+}
+
+func (c *schemaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *schemaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	out := fields[:len(fields):len(fields)]
+	for _, k := range c.keys {
+		if written, ok := c.entry[k]; ok {
+			if !written(ent) {
+				out = append(out, zap.String(k, ""))
+			}
+			continue
+		}
+		if _, ok := c.present[k]; ok || hasField(fields, k) {
+			continue
+		}
+		out = append(out, zap.String(k, ""))
+	}
+	return c.Core.Write(ent, out)
+}
+
+func hasField(fields []zapcore.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteCore 在编码前改写消息和字段，With 绑定的字段同样会被改写。
+// field 将改写后的结果追加到 out 中，可以丢弃字段或展开为多个字段
+type rewriteCore struct {
+	zapcore.Core
+	msg   func(string) string
+	field func(f zapcore.Field, out []zapcore.Field) []zapcore.Field
+}
+
+func (c *rewriteCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rewriteCore{Core: c.Core.With(c.rewrite(fields)), msg: c.msg, field: c.field}
+}
+
+func (c *rewriteCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *rewriteCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.msg != nil {
+		ent.Message = c.msg(ent.Message)
+	}
+	return c.Core.Write(ent, c.rewrite(fields))
+}
+
+func (c *rewriteCore) rewrite(fields []zapcore.Field) []zapcore.Field {
+	if c.field == nil || len(fields) == 0 {
+		return fields
+	}
+	out := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		out = c.field(f, out)
+	}
+	return out
+}
+
+// syncCore 写出 level 及以上的日志后立即 Sync，低级别日志仍由输出自行缓冲
+type syncCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c *syncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &syncCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *syncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if err := c.Core.Write(ent, fields); err != nil {
+		return err
+	}
+	if ent.Level >= c.level {
+		return c.Core.Sync()
+	}
+	return nil
+}
+
+// entryCore 在编码前改写 Entry，如去掉 caller、裁剪堆栈
+type entryCore struct {
+	zapcore.Core
+	edit func(zapcore.Entry) zapcore.Entry
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	return &entryCore{Core: c.Core.With(fields), edit: c.edit}
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(c.edit(ent), fields)
+}
+
+// omitCaller 去掉低于 min 级别日志的 caller，节省高频日志的体积
+func omitCaller(min zapcore.Level) func(zapcore.Entry) zapcore.Entry {
+	return func(ent zapcore.Entry) zapcore.Entry {
+		if ent.Level < min {
+			ent.Caller = zapcore.EntryCaller{}
+		}
+		return ent
+	}
+}
+
+// trimStack 只保留堆栈最上面的 n 帧。zap 的堆栈每帧两行：函数名和缩进的 文件:行号
+func trimStack(n int) func(zapcore.Entry) zapcore.Entry {
+	return func(ent zapcore.Entry) zapcore.Entry {
+		lines := 0
+		for i := 0; i < len(ent.Stack); i++ {
+			if ent.Stack[i] != '\n' {
+				continue
+			}
+			if lines++; lines == 2*n {
+				ent.Stack = ent.Stack[:i]
+				break
+			}
+		}
+		return ent
+	}
+}
@@ -0,0 +1,36 @@
+package prettyZap
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var counters = struct {
+	sync.Mutex
+	m map[string]int64
+}{m: make(map[string]int64)}
+
+// Count 将进程内名为 name 的计数器加上 delta，并以 info 级别记录一条消息为 name 的日志，
+// 附带 delta 和累计值 total。级别被过滤时计数照常增加，适合没有监控系统的批处理任务统计
+func Count(name string, delta int, fields ...zap.Field) {
+	counters.Lock()
+	counters.m[name] += int64(delta)
+	total := counters.m[name]
+	counters.Unlock()
+	if ce := plainLogger.Check(zapcore.InfoLevel, name); ce != nil {
+		ce.Write(append(fields[:len(fields):len(fields)], zap.Int("delta", delta), zap.Int64("total", total))...)
+	}
+}
+
+// Counters 返回所有计数器当前值的副本
+func Counters() map[string]int64 {
+	counters.Lock()
+	defer counters.Unlock()
+	m := make(map[string]int64, len(counters.m))
+	for k, v := range counters.m {
+		m[k] = v
+	}
+	return m
+}
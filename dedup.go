@@ -0,0 +1,96 @@
+package prettyZap
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// dedupCore 折叠 window 内连续重复的日志（级别和消息相同，不比较字段），
+// 消息变化或窗口结束时输出一条 "last message repeated N times"
+type dedupCore struct {
+	zapcore.Core
+	st *dedupState // 所有通过 With 派生的 core 共享
+}
+
+type dedupState struct {
+	mu         sync.Mutex
+	window     time.Duration
+	level      zapcore.Level
+	msg        string
+	name       string
+	core       zapcore.Core // 写出上一条日志的 core，汇总也写到这里
+	suppressed int
+	timer      *time.Timer
+}
+
+func newDedupCore(core zapcore.Core, window time.Duration) zapcore.Core {
+	return &dedupCore{Core: core, st: &dedupState{window: window}}
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), st: c.st}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	st := c.st
+	st.mu.Lock()
+	// 会导致 panic 或退出的日志总是写出
+	if st.core != nil && ent.Level == st.level && ent.Message == st.msg && ent.Level < zapcore.DPanicLevel {
+		st.suppressed++
+		if st.timer == nil {
+			st.timer = time.AfterFunc(st.window, st.expire)
+		}
+		st.mu.Unlock()
+		return nil
+	}
+	st.flush()
+	st.level, st.msg, st.name, st.core = ent.Level, ent.Message, ent.LoggerName, c.Core
+	st.mu.Unlock()
+	return c.Core.Write(ent, fields)
+}
+
+// Sync 先写出尚未输出的汇总，Shutdown、Drain 时不会丢失
+func (c *dedupCore) Sync() error {
+	c.st.mu.Lock()
+	c.st.flush()
+	c.st.mu.Unlock()
+	return c.Core.Sync()
+}
+
+// expire 在窗口结束时输出汇总，之后相同的消息重新开始计数
+func (st *dedupState) expire() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.flush()
+	st.core = nil
+}
+
+// flush 输出被折叠的条数，调用方持有锁
+func (st *dedupState) flush() {
+	if st.timer != nil {
+		st.timer.Stop()
+		st.timer = nil
+	}
+	if st.suppressed == 0 {
+		return
+	}
+	ent := zapcore.Entry{
+		Level:      st.level,
+		Time:       time.Now(),
+		LoggerName: st.name,
+		Message:    fmt.Sprintf("last message repeated %d times", st.suppressed),
+	}
+	st.core.Write(ent, []zapcore.Field{zap.String("repeatedMsg", st.msg), zap.Int("repeated", st.suppressed)})
+	st.suppressed = 0
+}
@@ -0,0 +1,31 @@
+package prettyZap
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDedupSummaryOnSync(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		sync func() error
+	}{
+		{"Sync", func() error { return zapLogger.Sync() }},
+		{"Drain", func() error { return Drain(context.Background()) }},
+	} {
+		cfg := DefaultCfg
+		cfg.DedupWindowMs = 60000
+		out := captureLogger(t, cfg)
+		for i := 0; i < 3; i++ {
+			Info("same message")
+		}
+		if err := tt.sync(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		lines := out.Lines()
+		if len(lines) != 2 || !strings.Contains(lines[1], `"msg":"last message repeated 2 times"`) {
+			t.Errorf("%s: want the message and a repeat summary, got:\n%s", tt.name, out)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package prettyZap
+
+// Disable 停止所有日志输出，包括 Named、Scope.Logger、Token.Logger 和存入 context 的 logger。
+// 各 logger 的 Check 会直接返回，不经过 sugar 的格式化。Panic、Fatal 仍会 panic 或退出。
+// 未初始化时什么也不做；之后调用 InitPrettyZap 会重新开启输出
+func Disable() {
+	if sinks != nil {
+		sinks.setDisabled(true)
+	}
+}
+
+// Enable 恢复 Disable 停止的输出，未禁用时什么也不做
+func Enable() {
+	if sinks != nil {
+		sinks.setDisabled(false)
+	}
+}
@@ -0,0 +1,66 @@
+package prettyZap
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDisableMutesDerivedLoggers(t *testing.T) {
+	out := captureLogger(t, DefaultCfg)
+	tok := Register(zap.Int("worker", 1))
+	defer tok.Release()
+	loggers := []*zap.SugaredLogger{
+		Named("db"),
+		NewScope(zap.String("request_id", "r1")).Logger(),
+		tok.Logger(),
+		FromContext(NewContext(context.Background(), plainLogger.Sugar())),
+	}
+
+	Disable()
+	Info("muted")
+	for _, log := range loggers {
+		log.Info("muted")
+	}
+	if got := out.String(); got != "" {
+		t.Fatalf("logs written while disabled:\n%s", got)
+	}
+
+	Enable()
+	Info("enabled")
+	for _, log := range loggers {
+		log.Info("enabled")
+	}
+	if got := len(out.Lines()); got != len(loggers)+1 {
+		t.Errorf("got %d lines after Enable, want %d:\n%s", got, len(loggers)+1, out)
+	}
+}
+
+func TestDisableConcurrentLogging(t *testing.T) {
+	captureLogger(t, DefaultCfg)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					Info("tick")
+					InfoFields("tick")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		Disable()
+		Enable()
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,112 @@
+package prettyZap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// closers 记录打开的日志文件，Drain 时关闭
+var closers = struct {
+	sync.Mutex
+	list []namedCloser
+}{}
+
+type namedCloser struct {
+	path string // 通过 claimFile 占用的文件，关闭后释放；为空表示没有占用
+	c    io.Closer
+}
+
+func registerCloser(path string, c io.Closer) {
+	closers.Lock()
+	closers.list = append(closers.list, namedCloser{path: path, c: c})
+	closers.Unlock()
+}
+
+// closeStreamFiles 关闭 JSON 数组文件和 gzip 流文件，写入数组结尾和 gzip 结尾使其可以完整解析，
+// 并释放对文件的占用。其他文件保持打开，Shutdown 之后仍可写入
+func closeStreamFiles() error {
+	closers.Lock()
+	defer closers.Unlock()
+	var err error
+	rest := closers.list[:0]
+	for _, nc := range closers.list {
+		if !isStreamCloser(nc.c) {
+			rest = append(rest, nc)
+			continue
+		}
+		if cerr := nc.c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if nc.path != "" {
+			releaseFile(nc.path)
+		}
+	}
+	closers.list = rest
+	return err
+}
+
+func isStreamCloser(c io.Closer) bool {
+	switch c := c.(type) {
+	case *jsonArraySyncer, *gzipSyncer:
+		return true
+	case *csvHeaderSyncer:
+		inner, ok := c.WriteSyncer.(io.Closer)
+		return ok && isStreamCloser(inner)
+	}
+	return false
+}
+
+// Drain 用于滚动发布时交接日志文件：之后的日志调用不再输出，已写出的日志刷新落盘后关闭所有日志文件，
+// 新进程可以安全地接管同一路径。ctx 结束时不再等待，返回 ctx.Err()
+func Drain(ctx context.Context) error {
+	if sinks == nil {
+		return errors.New("prettyZap is not initialized")
+	}
+	if rootCore != nil {
+		// 先通过 root core 同步，DedupWindowMs 折叠的汇总等暂存在 core 中的日志在切断输出前写出
+		flushed := make(chan struct{})
+		go func() {
+			rootCore.Sync()
+			close(flushed)
+		}()
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+		}
+	}
+	sinks.mu.Lock()
+	old := sinks.tee
+	sinks.base = zapcore.NewNopCore()
+	sinks.outputs = nil
+	sinks.extra = make(map[string]zapcore.Core)
+	sinks.rebuild()
+	sinks.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		err := old.Sync()
+		closers.Lock()
+		list := closers.list
+		closers.list = nil
+		closers.Unlock()
+		for _, nc := range list {
+			if cerr := nc.c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+			if nc.path != "" {
+				releaseFile(nc.path)
+			}
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,232 @@
+package prettyZap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	EncodingJSON    = "json"
+	EncodingCSV     = "csv"
+	EncodingLogfmt  = "logfmt"
+	EncodingConsole = "console" // zap 的纯文本格式：时间 级别 caller 消息 {字段}
+)
+
+var bufferPool = buffer.NewPool()
+
+func newEncoder(cfg *PreSetConfig) zapcore.Encoder {
+	encCfg := newEncoderConfig(cfg)
+	switch cfg.Encoding {
+	case EncodingCSV:
+		return newCSVEncoder(encCfg, cfg.CSVColumns)
+	case EncodingLogfmt:
+		return newLogfmtEncoder(encCfg)
+	case EncodingConsole:
+		return zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return zapcore.NewJSONEncoder(encCfg)
+	}
+}
+
+// csvEncoder 每条日志输出一行 CSV，表头由输出写入，见 csvHeader。
+// 列名为编码器配置中的 key（time、level、msg 等）或字段名，缺失的列留空
+type csvEncoder struct {
+	*zapcore.MapObjectEncoder // 通过 With 添加的字段
+	cfg                       zapcore.EncoderConfig
+	columns                   []string
+}
+
+func newCSVEncoder(cfg zapcore.EncoderConfig, columns []string) *csvEncoder {
+	return &csvEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              cfg,
+		columns:          csvColumns(cfg, columns),
+	}
+}
+
+func csvColumns(cfg zapcore.EncoderConfig, columns []string) []string {
+	if len(columns) == 0 {
+		return []string{cfg.TimeKey, cfg.LevelKey, cfg.MessageKey}
+	}
+	return columns
+}
+
+// csvHeader 返回 CSV 表头行。表头由输出在文件为空、轮转出新文件时写入，控制台只写一次，
+// 进程重启后追加写同一文件、SetEncoder 重建编码器时都不会重复写表头
+func csvHeader(cfg *PreSetConfig) []byte {
+	encCfg := newEncoderConfig(cfg)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = encCfg.LineEnding == "\r\n"
+	w.Write(csvColumns(encCfg, cfg.CSVColumns))
+	w.Flush()
+	return buf.Bytes()
+}
+
+// csvHeaderSyncer 在第一次写入前调用 empty 判断输出是否为空，为空时先写表头；empty 为 nil 时总是写
+type csvHeaderSyncer struct {
+	zapcore.WriteSyncer
+	header []byte
+	empty  func() bool
+	once   *sync.Once
+}
+
+func newCSVHeaderSyncer(ws zapcore.WriteSyncer, header []byte, empty func() bool) *csvHeaderSyncer {
+	return &csvHeaderSyncer{WriteSyncer: ws, header: header, empty: empty, once: new(sync.Once)}
+}
+
+func (s *csvHeaderSyncer) Write(p []byte) (int, error) {
+	// 并发写入的调用方在 Do 返回前等待，表头总在第一行
+	s.once.Do(func() {
+		if s.empty == nil || s.empty() {
+			s.WriteSyncer.Write(s.header)
+		}
+	})
+	return s.WriteSyncer.Write(p)
+}
+
+// Close 关闭内层输出，fileSyncer 据此注册关闭
+func (s *csvHeaderSyncer) Close() error {
+	if c, ok := s.WriteSyncer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// fileIsEmpty 返回判断 f 是否为空的函数，供 csvHeaderSyncer 使用
+func fileIsEmpty(f *os.File) func() bool {
+	return func() bool {
+		info, err := f.Stat()
+		return err == nil && info.Size() == 0
+	}
+}
+
+func (e *csvEncoder) Clone() zapcore.Encoder {
+	return &csvEncoder{
+		MapObjectEncoder: e.cloneFields(),
+		cfg:              e.cfg,
+		columns:          e.columns,
+	}
+}
+
+func (e *csvEncoder) cloneFields() *zapcore.MapObjectEncoder {
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		m.Fields[k] = v
+	}
+	return m
+}
+
+func (e *csvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := e.cloneFields()
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+	row := make([]string, len(e.columns))
+	for i, col := range e.columns {
+		row[i] = e.column(col, ent, m.Fields)
+	}
+
+	buf := bufferPool.Get()
+	w := csv.NewWriter(buf)
+	w.UseCRLF = e.cfg.LineEnding == "\r\n"
+	w.Write(row)
+	w.Flush()
+	if err := w.Error(); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (e *csvEncoder) column(col string, ent zapcore.Entry, fields map[string]interface{}) string {
+	switch col {
+	case "":
+		return ""
+	case e.cfg.TimeKey:
+		return encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { e.encodeTime(ent.Time, enc) })
+	case e.cfg.LevelKey:
+		if e.cfg.EncodeLevel == nil {
+			return ent.Level.String()
+		}
+		return encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeLevel(ent.Level, enc) })
+	case e.cfg.MessageKey:
+		return ent.Message
+	case e.cfg.NameKey:
+		return ent.LoggerName
+	case e.cfg.CallerKey:
+		if ent.Caller.Defined {
+			return ent.Caller.TrimmedPath()
+		}
+		return ""
+	case e.cfg.StacktraceKey:
+		return ent.Stack
+	}
+	if v, ok := fields[col]; ok {
+		return formatValue(v)
+	}
+	return ""
+}
+
+func (e *csvEncoder) encodeTime(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	if e.cfg.EncodeTime == nil {
+		enc.AppendString(t.Format(time.RFC3339Nano))
+		return
+	}
+	e.cfg.EncodeTime(t, enc)
+}
+
+// formatValue 将 MapObjectEncoder 收集到的字段值转为文本，嵌套对象、数组和反射值编码为 JSON
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case time.Time, time.Duration:
+		return fmt.Sprint(val)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// encodePrimitive 调用 zap 的时间、级别等编码函数并取得其文本输出
+func encodePrimitive(f func(zapcore.PrimitiveArrayEncoder)) string {
+	var p primitiveCapture
+	f(&p)
+	return p.s
+}
+
+type primitiveCapture struct {
+	s string
+}
+
+func (p *primitiveCapture) set(v interface{})             { p.s = fmt.Sprint(v) }
+func (p *primitiveCapture) AppendBool(v bool)             { p.set(v) }
+func (p *primitiveCapture) AppendByteString(v []byte)     { p.s = string(v) }
+func (p *primitiveCapture) AppendComplex128(v complex128) { p.set(v) }
+func (p *primitiveCapture) AppendComplex64(v complex64)   { p.set(v) }
+func (p *primitiveCapture) AppendFloat64(v float64)       { p.set(v) }
+func (p *primitiveCapture) AppendFloat32(v float32)       { p.set(v) }
+func (p *primitiveCapture) AppendInt(v int)               { p.set(v) }
+func (p *primitiveCapture) AppendInt64(v int64)           { p.set(v) }
+func (p *primitiveCapture) AppendInt32(v int32)           { p.set(v) }
+func (p *primitiveCapture) AppendInt16(v int16)           { p.set(v) }
+func (p *primitiveCapture) AppendInt8(v int8)             { p.set(v) }
+func (p *primitiveCapture) AppendString(v string)         { p.s = v }
+func (p *primitiveCapture) AppendUint(v uint)             { p.set(v) }
+func (p *primitiveCapture) AppendUint64(v uint64)         { p.set(v) }
+func (p *primitiveCapture) AppendUint32(v uint32)         { p.set(v) }
+func (p *primitiveCapture) AppendUint16(v uint16)         { p.set(v) }
+func (p *primitiveCapture) AppendUint8(v uint8)           { p.set(v) }
+func (p *primitiveCapture) AppendUintptr(v uintptr)       { p.set(v) }
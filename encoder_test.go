@@ -0,0 +1,109 @@
+package prettyZap
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const testCSVHeader = "time,level,msg\n"
+
+func TestCSVHeaderOncePerFile(t *testing.T) {
+	for _, noRotation := range []bool{false, true} {
+		cfg := DefaultCfg
+		cfg.Encoding = EncodingCSV
+		cfg.NoRotation = noRotation
+		cfg.LogFilePath = filepath.Join(t.TempDir(), "app.csv")
+		// 模拟进程重启：两次打开同一文件追加写
+		for i := 0; i < 2; i++ {
+			ws, err := openFileSyncer(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ws.Write([]byte("t,info,hello\n"))
+			ws.(io.Closer).Close()
+		}
+		data, err := ioutil.ReadFile(cfg.LogFilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := testCSVHeader + "t,info,hello\nt,info,hello\n"; string(data) != want {
+			t.Errorf("noRotation=%v: file content = %q, want %q", noRotation, data, want)
+		}
+	}
+}
+
+func TestCSVHeaderAfterRotation(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.Encoding = EncodingCSV
+	cfg.MaxLogSizeMb = 1
+	cfg.LogFilePath = filepath.Join(t.TempDir(), "app.csv")
+	ws, err := openFileSyncer(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.(io.Closer).Close()
+	row := []byte("t,info," + strings.Repeat("x", 1000) + "\n")
+	for i := 0; i < 1100; i++ {
+		if _, err := ws.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backup := lastBackup(cfg.LogFilePath)
+	if backup == "" {
+		t.Fatal("no rotation happened")
+	}
+	for _, path := range []string{backup, cfg.LogFilePath} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), testCSVHeader) || strings.Count(string(data), testCSVHeader) != 1 {
+			t.Errorf("%s should start with exactly one header", filepath.Base(path))
+		}
+	}
+}
+
+func TestCSVHeaderOnceOnConsole(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.Encoding = EncodingCSV
+	console := &lockedBuffer{}
+	out := &coreOutputs{console: []zapcore.WriteSyncer{console}, consoleHeaders: []*sync.Once{new(sync.Once)}}
+	// SetEncoder 会按同一组输出重建 core
+	for i := 0; i < 2; i++ {
+		buildCore(&cfg, out, cfg.Encoding).Write(zapcore.Entry{Message: "hello"}, nil)
+	}
+	if got := console.String(); strings.Count(got, "time,level,msg") != 1 {
+		t.Errorf("console output should contain one header:\n%s", got)
+	}
+}
+
+func TestLevelCaseWithPreset(t *testing.T) {
+	tests := []struct {
+		preset string
+		want   string
+	}{
+		{"", `"level":"Warn"`},
+		{PresetECS, `"log.level":"Warn"`},
+		// gcp 的 severity 取值固定，不受 LevelCase 影响
+		{PresetGCP, `"severity":"WARNING"`},
+	}
+	for _, tt := range tests {
+		cfg := DefaultCfg
+		cfg.Preset = tt.preset
+		cfg.LevelCase = "capital"
+		buf, err := newEncoder(&cfg).EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "hello"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("preset %q: %s, want %s", tt.preset, buf.String(), tt.want)
+		}
+		buf.Free()
+	}
+}
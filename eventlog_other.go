@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package prettyZap
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newEventLogCore(*PreSetConfig, string) (zapcore.Core, error) {
+	return nil, errors.New("windows event log is only supported on windows")
+}
@@ -0,0 +1,61 @@
+//go:build windows
+// +build windows
+
+package prettyZap
+
+import (
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventLogCore 将日志写入 Windows 事件日志，warn 对应警告、error 及以上对应错误，其余为信息
+type eventLogCore struct {
+	enc zapcore.Encoder
+	log *eventlog.Log
+}
+
+// newEventLogCore 打开名为 source 的事件源，事件源需要预先注册（如 eventcreate 或安装程序）
+func newEventLogCore(cfg *PreSetConfig, source string) (zapcore.Core, error) {
+	log, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogCore{enc: newEncoder(cfg), log: log}, nil
+}
+
+func (c *eventLogCore) Enabled(zapcore.Level) bool {
+	return true // 级别由外层 levelCore 控制
+}
+
+func (c *eventLogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &eventLogCore{enc: enc, log: c.log}
+}
+
+func (c *eventLogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *eventLogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := buf.String()
+	buf.Free()
+	switch {
+	case ent.Level >= zapcore.ErrorLevel:
+		return c.log.Error(1, msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.log.Warning(1, msg)
+	default:
+		return c.log.Info(1, msg)
+	}
+}
+
+func (c *eventLogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package prettyZap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Proto 将 protobuf 消息按 protojson 编码为一个结构化字段，编码延迟到日志实际写出时进行
+func Proto(key string, m proto.Message) zap.Field {
+	return zap.Reflect(key, protoJSON{m})
+}
+
+type protoJSON struct {
+	m proto.Message
+}
+
+func (p protoJSON) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(p.m)
+}
+
+// Lazy 返回延迟求值的字段，fn 只在日志真正写出时调用，返回的字段平铺在日志中。
+// 级别被过滤掉的日志不会调用 fn，适合开销较大的调试字段
+func Lazy(fn func() []zap.Field) zap.Field {
+	return zap.Inline(lazyFields(fn))
+}
+
+// lazyFields 是 Lazy 字段的值，flattenCore 据此识别并推迟到 Write 时才求值
+type lazyFields func() []zap.Field
+
+func (fn lazyFields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range fn() {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+// Object 将任意值（通常是结构体）按 JSON 嵌套在 key 下，而不是展开到消息里。
+// 可以直接作为 Info 等函数的参数：Info("loaded", Object("user", u))
+func Object(key string, v interface{}) zap.Field {
+	return zap.Any(key, v)
+}
+
+// Any 与 zap.Any 相同，map、切片按 JSON 输出而不是 Go 的 map[...] 格式，
+// 可以作为 Info 等函数或 InfoFields 等函数的参数：Info("stats", Any("counts", m))
+func Any(key string, v interface{}) zap.Field {
+	return zap.Any(key, v)
+}
+
+// Int、Int64、Float64 生成确定类型的字段，整数始终编码为 JSON 整数，
+// 可以直接作为 Info 等函数的参数：Info("done", Int("count", n))
+func Int(key string, v int) zap.Field {
+	return zap.Int(key, v)
+}
+
+func Int64(key string, v int64) zap.Field {
+	return zap.Int64(key, v)
+}
+
+func Float64(key string, v float64) zap.Field {
+	return zap.Float64(key, v)
+}
+
+// Fingerprint 输出 secret 的 SHA-256 前 8 字节，形如 "sha256:1a2b3c4d5e6f7a8b"，
+// 用于记录密钥轮换等事件：能比对前后是否为同一密钥，而不会泄露密钥本身。
+// key 在 RedactKeys 中时指纹照常输出，不会被替换为 ***
+func Fingerprint(key string, secret []byte) zap.Field {
+	return zap.Stringer(key, newFingerprint(secret))
+}
+
+// fingerprint 是 Fingerprint 字段的值，redactFields 据此识别已经处理过的字段
+type fingerprint string
+
+func newFingerprint(secret []byte) fingerprint {
+	sum := sha256.Sum256(secret)
+	return fingerprint("sha256:" + hex.EncodeToString(sum[:8]))
+}
+
+func (f fingerprint) String() string {
+	return string(f)
+}
+
+// redactFields 将 key 在 keys 中（不区分大小写）的字段替换为 ***。不使用哈希：
+// 密码、证件号等取值空间小的内容可以通过字典还原。Fingerprint 字段保留
+func redactFields(keys []string) func(zapcore.Field, []zapcore.Field) []zapcore.Field {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[strings.ToLower(k)] = true
+	}
+	return func(f zapcore.Field, out []zapcore.Field) []zapcore.Field {
+		if !set[strings.ToLower(f.Key)] {
+			return append(out, f)
+		}
+		if _, ok := f.Interface.(fingerprint); ok && f.Type == zapcore.StringerType {
+			return append(out, f)
+		}
+		return append(out, zap.String(f.Key, "***"))
+	}
+}
@@ -0,0 +1,54 @@
+package prettyZap
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRedactKeys(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.RedactKeys = []string{"password", "api_key"}
+	out := captureLogger(t, cfg)
+
+	InfoFields("login", Any("Password", "hunter2"), Fingerprint("api_key", []byte("secret-key")))
+	line := out.String()
+	if strings.Contains(line, "hunter2") {
+		t.Fatalf("password leaked: %s", line)
+	}
+	if !strings.Contains(line, `"Password":"***"`) {
+		t.Errorf("RedactKeys value should be replaced with ***: %s", line)
+	}
+	if want := `"api_key":"` + newFingerprint([]byte("secret-key")).String() + `"`; !strings.Contains(line, want) {
+		t.Errorf("Fingerprint field should be kept as %s: %s", want, line)
+	}
+}
+
+func TestLazyWithFlattenFields(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.FlattenFields = true
+	cfg.LogLevel = "info"
+	out := captureLogger(t, cfg)
+
+	calls := 0
+	log := plainLogger.With(zap.Namespace("req"), Lazy(func() []zap.Field {
+		calls++
+		return []zap.Field{zap.Int("status", 200)}
+	}))
+	if calls != 0 {
+		t.Fatalf("Lazy evaluated %d times by With", calls)
+	}
+	log.Debug("filtered")
+	if calls != 0 {
+		t.Fatalf("Lazy evaluated for a filtered entry")
+	}
+	log.Info("first")
+	log.Info("second")
+	if calls != 2 {
+		t.Errorf("Lazy should be evaluated once per written entry, got %d", calls)
+	}
+	if lines := out.Lines(); len(lines) != 2 || !strings.Contains(lines[0], `"req.status":200`) {
+		t.Errorf("Lazy fields not flattened under the namespace: %q", out.String())
+	}
+}
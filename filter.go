@@ -0,0 +1,58 @@
+package prettyZap
+
+import (
+	"hash/fnv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// filterCore 用 keep 决定每条日志是否写出，keep 能看到通过 With 绑定的字段和本次的字段
+type filterCore struct {
+	zapcore.Core
+	keep func(zapcore.Entry, []zapcore.Field) bool
+	with []zapcore.Field
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filterCore{
+		Core: c.Core.With(fields),
+		keep: c.keep,
+		with: append(c.with[:len(c.with):len(c.with)], fields...),
+	}
+}
+
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := fields
+	if len(c.with) > 0 {
+		all = append(c.with[:len(c.with):len(c.with)], fields...)
+	}
+	if !c.keep(ent, all) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// SampleKey 返回用于 LogFilter 的判断函数：按字段 key 的值取哈希，固定保留约 percent% 的实体
+// （如用户）的全部日志，不带该字段的日志总是保留。级别不低于 minLevel 的日志不参与采样。
+// 例如级别设为 debug，SampleKey("user_id", 5, zapcore.InfoLevel) 只为约 5% 的用户输出 debug 日志
+func SampleKey(key string, percent int, minLevel zapcore.Level) func(zapcore.Entry, []zapcore.Field) bool {
+	return func(ent zapcore.Entry, fields []zapcore.Field) bool {
+		if ent.Level >= minLevel {
+			return true
+		}
+		value, ok := routeValue(fields, key)
+		if !ok {
+			return true
+		}
+		h := fnv.New32a()
+		h.Write([]byte(value))
+		return int(h.Sum32()%100) < percent
+	}
+}
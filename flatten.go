@@ -0,0 +1,142 @@
+package prettyZap
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// flattenCore 将嵌套对象展开为用 delim 连接的平铺 key，如 http.status。
+// Namespace 同样转为 key 前缀，With 中打开的 Namespace 对之后的字段继续生效。
+// With 中的 Lazy 字段连同当时的前缀保存在 lazy 中，到 Write 时才求值
+type flattenCore struct {
+	zapcore.Core
+	delim  string
+	prefix string
+	lazy   []lazyField
+}
+
+type lazyField struct {
+	prefix string
+	field  zapcore.Field
+}
+
+func newFlattenCore(core zapcore.Core, delim string) zapcore.Core {
+	if delim == "" {
+		delim = "."
+	}
+	return &flattenCore{Core: core, delim: delim}
+}
+
+func (c *flattenCore) With(fields []zapcore.Field) zapcore.Core {
+	lazy := c.lazy[:len(c.lazy):len(c.lazy)]
+	flat, prefix := c.flatten(fields, &lazy)
+	return &flattenCore{Core: c.Core.With(flat), delim: c.delim, prefix: prefix, lazy: lazy}
+}
+
+func (c *flattenCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *flattenCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.lazy) == 0 {
+		flat, _ := c.flatten(fields, nil)
+		return c.Core.Write(ent, flat)
+	}
+	var out []zapcore.Field
+	for _, l := range c.lazy {
+		out = c.flattenObject(l.prefix, l.field, out)
+	}
+	flat, _ := c.flatten(fields, nil)
+	return c.Core.Write(ent, append(out, flat...))
+}
+
+// flatten 展开 fields，返回展开后的字段和其后生效的 key 前缀。
+// lazy 不为 nil 时 Lazy 字段不求值，连同前缀追加到 lazy 中
+func (c *flattenCore) flatten(fields []zapcore.Field, lazy *[]lazyField) ([]zapcore.Field, string) {
+	prefix := c.prefix
+	out := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if _, ok := f.Interface.(lazyFields); ok && lazy != nil {
+			*lazy = append(*lazy, lazyField{prefix: prefix, field: f})
+			continue
+		}
+		switch f.Type {
+		case zapcore.NamespaceType:
+			prefix += f.Key + c.delim
+		case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+			out = c.flattenObject(prefix, f, out)
+		case zapcore.ReflectType:
+			v, ok := jsonValue(f.Interface)
+			if !ok {
+				f.Key = prefix + f.Key
+				out = append(out, f)
+				continue
+			}
+			out = c.flattenValue(prefix+f.Key, v, out)
+		default:
+			f.Key = prefix + f.Key
+			out = append(out, f)
+		}
+	}
+	return out, prefix
+}
+
+func (c *flattenCore) flattenObject(prefix string, f zapcore.Field, out []zapcore.Field) []zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	for _, k := range sortedKeys(enc.Fields) {
+		out = c.flattenValue(prefix+k, enc.Fields[k], out)
+	}
+	return out
+}
+
+func (c *flattenCore) flattenValue(key string, v interface{}, out []zapcore.Field) []zapcore.Field {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			break
+		}
+		for _, k := range sortedKeys(v) {
+			out = c.flattenValue(key+c.delim+k, v[k], out)
+		}
+		return out
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return append(out, zap.Int64(key, n))
+		}
+		f, _ := v.Float64()
+		return append(out, zap.Float64(key, f))
+	}
+	return append(out, zap.Any(key, v))
+}
+
+// jsonValue 将任意值经 JSON 转为 map、slice 等基本类型，数字保留为 json.Number 避免丢失精度
+func jsonValue(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
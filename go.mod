@@ -4,5 +4,7 @@ go 1.16
 
 require (
 	go.uber.org/zap v1.24.0
+	golang.org/x/sys v0.0.0-20210510120138-977fb7262007
+	google.golang.org/protobuf v1.30.0
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
-)
\ No newline at end of file
+)
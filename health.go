@@ -0,0 +1,95 @@
+package prettyZap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 最近一次写日志文件是否失败，1 表示失败
+var fileWriteFailing int32
+
+var fileWriteErrors uint64
+
+// FileWriteErrors 返回写日志文件失败的次数。zap 会丢弃写入错误，可以据此告警
+func FileWriteErrors() uint64 {
+	return atomic.LoadUint64(&fileWriteErrors)
+}
+
+// fileStatusSyncer 记录文件写入结果，供健康检查使用。
+// 写入从成功变为失败时向 stderr 输出一次警告，恢复后再次失败会重新警告
+type fileStatusSyncer struct {
+	zapcore.WriteSyncer
+	path string
+}
+
+func (s fileStatusSyncer) Write(p []byte) (int, error) {
+	n, err := s.WriteSyncer.Write(p)
+	if err != nil {
+		atomic.AddUint64(&fileWriteErrors, 1)
+		if atomic.CompareAndSwapInt32(&fileWriteFailing, 0, 1) {
+			warnf("write log file %s failed, entries are being lost: %v", s.path, err)
+		}
+	} else {
+		atomic.StoreInt32(&fileWriteFailing, 0)
+	}
+	return n, err
+}
+
+var outputNames = []struct {
+	target int
+	name   string
+}{
+	{OutputStdout, "stdout"},
+	{OutputStderr, "stderr"},
+	{OutputFile, "file"},
+	{OutputRemote, "remote"},
+	{OutputEventLog, "eventlog"},
+}
+
+// healthHandler 返回日志组件自身的状态，始终返回 200
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	targets := outputTargets(&DefaultCfg)
+	outputs := []string{}
+	for _, o := range outputNames {
+		if targets&o.target != 0 {
+			outputs = append(outputs, o.name)
+		}
+	}
+	status := map[string]interface{}{
+		"status":  "ok",
+		"level":   atomicLevel.Level().String(),
+		"outputs": outputs,
+	}
+	if targets&OutputFile != 0 {
+		status["fileWritesOK"] = atomic.LoadInt32(&fileWriteFailing) == 0
+		status["fileWriteErrors"] = FileWriteErrors()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// VerifyWrite 同步写出一条 info 日志并 Sync，返回写入失败的错误，用于启动时确认日志输出可用，
+// 如日志目录不可写时尽早失败。该日志不受级别、采样、过滤等配置影响；远程输出是异步的，不在检查范围内
+func VerifyWrite() error {
+	if sinks == nil {
+		return errors.New("prettyZap is not initialized")
+	}
+	_, tee := sinks.current()
+	before := FileWriteErrors()
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "prettyZap write check"}
+	err := tee.With(globalFields(&DefaultCfg)).Write(ent, nil)
+	if serr := tee.Sync(); err == nil {
+		err = serr
+	}
+	// 异步模式下写入错误不会返回给调用方，Sync 之后通过失败计数判断
+	if err == nil && FileWriteErrors() != before {
+		err = fmt.Errorf("write log file %s failed", DefaultCfg.LogFilePath)
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package prettyZap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// startJanitor 定期检查日志占用的空间，超过 maxMb 时按修改时间从旧到新删除轮转出的备份文件。
+// 只统计 LogFilePath、ExtraFiles、RouteDir 下的路由文件及它们的备份，同目录下的可执行文件、配置等不计入；
+// 正在写入的文件不会被删除。lumberjack 仍按 MaxBackup、MaxAgeDay 清理，两者删除同一文件时忽略文件不存在的错误
+func startJanitor(cfg *PreSetConfig, maxMb int, interval time.Duration) {
+	logFiles := []string{cfg.LogFilePath}
+	for _, sink := range cfg.ExtraFiles {
+		logFiles = append(logFiles, sink.Path)
+	}
+	var routeDir string
+	if cfg.RouteKey != "" {
+		routeDir = cfg.RouteDir
+		if routeDir == "" {
+			routeDir = filepath.Dir(cfg.LogFilePath)
+		}
+	}
+	budget := int64(maxMb) * 1024 * 1024
+	over := false
+	check := func() {
+		total := enforceDirBudget(logFiles, routeDir, budget)
+		// 只在从未超出变为超出时警告，避免每次检查都输出
+		if total > budget && !over {
+			warnf("log files still use %d MB after removing all backups, over MaxLogDirMb", total/1024/1024)
+		}
+		over = total > budget
+	}
+	check()
+	go func() {
+		for range time.Tick(interval) {
+			check()
+		}
+	}()
+}
+
+type backupFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// enforceDirBudget 删除最旧的备份直到日志文件总大小不超过 budget，返回删除后的总大小。
+// routeDir 为空表示没有路由文件
+func enforceDirBudget(logFiles []string, routeDir string, budget int64) int64 {
+	dirs := make(map[string]bool)
+	for _, logFile := range logFiles {
+		dirs[filepath.Clean(filepath.Dir(logFile))] = true
+	}
+	if routeDir != "" {
+		routeDir = filepath.Clean(routeDir)
+		dirs[routeDir] = true
+	}
+	active := make(map[string]bool, len(logFiles))
+	for _, logFile := range logFiles {
+		active[filepath.Clean(logFile)] = true
+	}
+	var total int64
+	var backups []backupFile
+	for dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			path := filepath.Join(dir, info.Name())
+			inRouteDir := dir == routeDir
+			switch {
+			case active[path]:
+			case isBackupOf(path, logFiles), inRouteDir && isRouteBackup(info.Name()):
+				backups = append(backups, backupFile{path: path, size: info.Size(), modTime: info.ModTime()})
+			case inRouteDir && isRouteFile(info.Name()):
+			default:
+				continue // 不是日志文件，不计入
+			}
+			total += info.Size()
+		}
+	}
+	if total <= budget {
+		return total
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.Before(backups[j].modTime) })
+	for _, b := range backups {
+		if total <= budget {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			warnf("remove old log file %s failed: %v", b.path, err)
+			continue
+		}
+		total -= b.size
+	}
+	return total
+}
+
+// isBackupOf 判断 path 是否为 logFiles 之一轮转出的备份，同名前缀的其他日志文件（如 app-json.log）不算
+func isBackupOf(path string, logFiles []string) bool {
+	for _, logFile := range logFiles {
+		if filepath.Dir(path) == filepath.Dir(logFile) && isBackupName(logFile, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRouteFile 判断 name 是否为路由文件（见 routeFileName）或其备份
+func isRouteFile(name string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(name, ".gz"), ".log")
+}
+
+// isRouteBackup 判断 name 是否为路由文件轮转出的备份：<值>-时间戳.log，压缩后追加 .gz
+func isRouteBackup(name string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasSuffix(name, ".log") {
+		return false
+	}
+	stem := strings.TrimSuffix(name, ".log")
+	i := len(stem) - len(backupTimeFormat) - 1
+	if i < 1 || stem[i] != '-' {
+		return false
+	}
+	return isBackupName(stem[:i]+".log", name)
+}
@@ -0,0 +1,57 @@
+package prettyZap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, size int, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnforceDirBudgetKeepsActiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"app.log",
+		"app-json.log",
+		"app-2020-01-01T00-00-00.000.log",
+		"app-json-2020-01-01T00-00-00.000.log",
+	}
+	writeFiles(t, dir, 1024, files...)
+	// 同目录下的可执行文件不计入日志占用
+	writeFiles(t, dir, 100*1024, "server")
+	logFiles := []string{filepath.Join(dir, "app.log"), filepath.Join(dir, "app-json.log")}
+	// 预算只够保留两个文件，两个备份都应删除，正在写入的文件保留
+	if total := enforceDirBudget(logFiles, "", 2*1024); total != 2*1024 {
+		t.Errorf("total = %d, want %d", total, 2*1024)
+	}
+	for i, name := range append(files, "server") {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != (i < 2 || name == "server") {
+			t.Errorf("%s exists = %v", name, exists)
+		}
+	}
+}
+
+func TestEnforceDirBudgetRouteFiles(t *testing.T) {
+	dir, routeDir := t.TempDir(), t.TempDir()
+	writeFiles(t, dir, 1024, "app.log")
+	writeFiles(t, routeDir, 1024, "42.log", "42-2020-01-01T00-00-00.000.log.gz", "notes.txt")
+	total := enforceDirBudget([]string{filepath.Join(dir, "app.log")}, routeDir, 2*1024)
+	if total != 2*1024 {
+		t.Errorf("total = %d, want %d", total, 2*1024)
+	}
+	if _, err := os.Stat(filepath.Join(routeDir, "42-2020-01-01T00-00-00.000.log.gz")); !os.IsNotExist(err) {
+		t.Error("route backup should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(routeDir, "42.log")); err != nil {
+		t.Error("active route file should be kept")
+	}
+}
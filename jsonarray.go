@@ -0,0 +1,101 @@
+package prettyZap
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// jsonArraySyncer 将逐行的 JSON 日志写成一个 JSON 数组：打开时写入 "["，条目之间以逗号分隔，
+// Close 时写入 "]"。只适用于 json 编码，不轮转
+type jsonArraySyncer struct {
+	mu     sync.Mutex
+	f      *os.File
+	first  bool // 下一条是数组的第一个元素，不需要逗号
+	closed bool
+}
+
+// jsonArrayTail 打开已有文件时检查的结尾长度，用于识别上次正常关闭留下的 "]"
+const jsonArrayTail = 64
+
+// newJSONArraySyncer 接管以追加方式打开的 f。文件已是完整的数组时去掉结尾的 "]" 继续追加，
+// 上次未正常关闭时直接追加，Close 后仍是合法的数组
+func newJSONArraySyncer(f *os.File, path string) (*jsonArraySyncer, error) {
+	s := &jsonArraySyncer{f: f}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		s.first = true
+		_, err = f.Write([]byte("[\n"))
+		return s, err
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	off := info.Size() - jsonArrayTail
+	if off < 0 {
+		off = 0
+	}
+	tail := make([]byte, info.Size()-off)
+	if _, err := r.ReadAt(tail, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	tail = bytes.TrimRight(tail, " \t\r\n")
+	if bytes.HasSuffix(tail, []byte("]")) {
+		tail = bytes.TrimRight(tail[:len(tail)-1], " \t\r\n")
+		if err := f.Truncate(off + int64(len(tail))); err != nil {
+			return nil, err
+		}
+	}
+	s.first = bytes.HasSuffix(tail, []byte("["))
+	return s, nil
+}
+
+func (s *jsonArraySyncer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	// 去掉编码器追加的行尾，由分隔符负责换行
+	entry := bytes.TrimRight(p, "\r\n")
+	buf := make([]byte, 0, len(entry)+2)
+	if !s.first {
+		buf = append(buf, ",\n"...)
+	}
+	buf = append(buf, entry...)
+	if _, err := s.f.Write(buf); err != nil {
+		return 0, err
+	}
+	s.first = false
+	return len(p), nil
+}
+
+func (s *jsonArraySyncer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	return s.f.Sync()
+}
+
+// Close 写入结尾的 "]" 并关闭文件，之后的写入返回 os.ErrClosed
+func (s *jsonArraySyncer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if _, err := s.f.Write([]byte("\n]\n")); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
@@ -0,0 +1,131 @@
+package prettyZap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var namedLevels = struct {
+	sync.Mutex
+	m map[string]zap.AtomicLevel
+}{m: make(map[string]zap.AtomicLevel)}
+
+// Named 返回一个命名子 logger，它拥有独立的级别，
+// 可通过 HTTP 接口的 name 参数单独调整，例如 PUT /change/level?name=app.db
+func Named(name string) *zap.SugaredLogger {
+	log := newLogger(rootCore, namedLevel(name), &DefaultCfg)
+	// 子 logger 由调用方直接使用，抵消为包级函数设置的 caller skip
+	return log.WithOptions(zap.AddCallerSkip(-1)).Named(name).Sugar()
+}
+
+// namedLevel 返回 name 对应的级别，不存在时以当前全局级别创建
+func namedLevel(name string) zap.AtomicLevel {
+	namedLevels.Lock()
+	defer namedLevels.Unlock()
+	if lvl, ok := namedLevels.m[name]; ok {
+		return lvl
+	}
+	lvl := zap.NewAtomicLevelAt(atomicLevel.Level())
+	namedLevels.m[name] = lvl
+	return lvl
+}
+
+// lookupNamedLevel 返回 name 对应的级别，不存在时不创建
+func lookupNamedLevel(name string) (zap.AtomicLevel, bool) {
+	namedLevels.Lock()
+	defer namedLevels.Unlock()
+	lvl, ok := namedLevels.m[name]
+	return lvl, ok
+}
+
+// levelHandler 在 zap 的 GET/PUT JSON 接口之外，支持 GET ?level=debug 直接修改级别。
+// 级别可以是名称，也可以是 zap 的数值，如 -1 表示 debug
+func levelHandler(w http.ResponseWriter, r *http.Request) {
+	lvl := atomicLevel
+	if name := r.URL.Query().Get("name"); name != "" {
+		// 只有 PUT 创建新的命名级别，避免任意 GET 请求让 namedLevels 无限增长
+		var ok bool
+		if r.Method == http.MethodPut {
+			lvl, ok = namedLevel(name), true
+		} else {
+			lvl, ok = lookupNamedLevel(name)
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("unknown logger name: %q", name)})
+			return
+		}
+	}
+	switch text := r.URL.Query().Get("level"); {
+	case r.Method == http.MethodGet && text != "":
+		setLevelText(w, lvl, text)
+	case r.Method == http.MethodPut:
+		text, err := levelFromBody(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		setLevelText(w, lvl, text)
+	default:
+		lvl.ServeHTTP(w, r)
+	}
+}
+
+// levelFromBody 读取 PUT 请求中的级别，兼容 zap 的 JSON 和表单两种格式，JSON 中的级别可以是数字
+func levelFromBody(r *http.Request) (string, error) {
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		return r.FormValue("level"), nil
+	}
+	var req struct {
+		Level json.RawMessage `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", fmt.Errorf("request body must be well-formed JSON: %v", err)
+	}
+	return strings.Trim(string(req.Level), `"`), nil
+}
+
+func setLevelText(w http.ResponseWriter, lvl zap.AtomicLevel, text string) {
+	enc := json.NewEncoder(w)
+	level, err := ParseLevel(text)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		enc.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	lvl.SetLevel(level)
+	enc.Encode(map[string]string{"level": level.String()})
+}
+
+// ParseLevel 解析级别名称（不区分大小写）或 zap 的数值级别，数值超出 debug(-1) 到 fatal(5) 时报错
+func ParseLevel(text string) (zapcore.Level, error) {
+	if level, ok := levelMap[strings.ToLower(text)]; ok {
+		return level, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized level: %q", text)
+	}
+	if n < int(zapcore.DebugLevel) || n > int(zapcore.FatalLevel) {
+		return 0, fmt.Errorf("level %d out of range [%d, %d]", n, zapcore.DebugLevel, zapcore.FatalLevel)
+	}
+	return zapcore.Level(n), nil
+}
+
+// SetLevel 修改全局日志级别，level 可以是名称或 zap 的数值级别
+func SetLevel(level string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(l)
+	return nil
+}
@@ -0,0 +1,41 @@
+package prettyZap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandlerUnknownName(t *testing.T) {
+	const name = "test.level.handler"
+	t.Cleanup(func() {
+		namedLevels.Lock()
+		delete(namedLevels.m, name)
+		namedLevels.Unlock()
+	})
+	serve := func(method, query, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		levelHandler(rec, httptest.NewRequest(method, "/change/level?"+query, strings.NewReader(body)))
+		return rec
+	}
+
+	// GET 不创建命名级别
+	for _, query := range []string{"name=" + name, "name=" + name + "&level=debug"} {
+		if rec := serve(http.MethodGet, query, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET ?%s = %d, want 404", query, rec.Code)
+		}
+	}
+	if _, ok := lookupNamedLevel(name); ok {
+		t.Fatal("GET created a named level")
+	}
+
+	// PUT 创建，之后 GET 可以读取和修改
+	if rec := serve(http.MethodPut, "name="+name, `{"level":"warn"}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d: %s", rec.Code, rec.Body)
+	}
+	rec := serve(http.MethodGet, "name="+name, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"warn"`) {
+		t.Errorf("GET after PUT = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,38 @@
+package prettyZap
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// watchLevelFile 立即应用 path 中的级别，之后在后台轮询，内容变化时重新设置全局级别。
+// 使用轮询而不是 fsnotify：ConfigMap 通过替换符号链接更新，监听文件本身收不到事件
+func watchLevelFile(path string, interval time.Duration) {
+	last := applyLevelFile(path, "")
+	go func() {
+		for range time.Tick(interval) {
+			last = applyLevelFile(path, last)
+		}
+	}()
+}
+
+// applyLevelFile 在文件内容与 last 不同时应用级别，返回本次读到的内容。
+// 内容无效时输出警告并保持当前级别，同样的内容不会重复警告
+func applyLevelFile(path, last string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if last != "" {
+			warnf("read level file %s failed: %v", path, err)
+		}
+		return ""
+	}
+	text := strings.TrimSpace(string(b))
+	if text == last {
+		return last
+	}
+	if err := SetLevel(text); err != nil {
+		warnf("ignore level file %s: %v", path, err)
+	}
+	return text
+}
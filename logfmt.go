@@ -0,0 +1,210 @@
+package prettyZap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtEncoder 输出 key=value 形式的日志，如 time=... level=info msg="hello world" k=v。
+// 嵌套对象展开为 a.b=v，数组和反射值编码为 JSON 字符串
+type logfmtEncoder struct {
+	cfg    zapcore.EncoderConfig
+	buf    *buffer.Buffer // 通过 With 添加的字段，已编码
+	prefix string         // OpenNamespace 和嵌套对象产生的 key 前缀
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) *logfmtEncoder {
+	return &logfmtEncoder{cfg: cfg, buf: bufferPool.Get()}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	buf := bufferPool.Get()
+	buf.Write(e.buf.Bytes())
+	return &logfmtEncoder{cfg: e.cfg, buf: buf, prefix: e.prefix}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := &logfmtEncoder{cfg: e.cfg, buf: bufferPool.Get()}
+	if e.cfg.TimeKey != "" {
+		line.AddTime(e.cfg.TimeKey, ent.Time)
+	}
+	if e.cfg.LevelKey != "" {
+		if e.cfg.EncodeLevel == nil {
+			line.AddString(e.cfg.LevelKey, ent.Level.String())
+		} else {
+			line.AddString(e.cfg.LevelKey, encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeLevel(ent.Level, enc) }))
+		}
+	}
+	if ent.LoggerName != "" && e.cfg.NameKey != "" {
+		line.AddString(e.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && e.cfg.CallerKey != "" {
+		if e.cfg.EncodeCaller == nil {
+			line.AddString(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+		} else {
+			line.AddString(e.cfg.CallerKey, encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeCaller(ent.Caller, enc) }))
+		}
+	}
+	if e.cfg.MessageKey != "" {
+		line.AddString(e.cfg.MessageKey, ent.Message)
+	}
+	if e.buf.Len() > 0 {
+		line.separate()
+		line.buf.Write(e.buf.Bytes())
+	}
+	line.prefix = e.prefix
+	for _, f := range fields {
+		f.AddTo(line)
+	}
+	line.prefix = ""
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" {
+		line.AddString(e.cfg.StacktraceKey, ent.Stack)
+	}
+	if e.cfg.LineEnding != "" {
+		line.buf.AppendString(e.cfg.LineEnding)
+	} else {
+		line.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return line.buf, nil
+}
+
+func (e *logfmtEncoder) separate() {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+}
+
+func (e *logfmtEncoder) addKey(key string) {
+	e.separate()
+	e.buf.AppendString(e.prefix)
+	e.buf.AppendString(key)
+	e.buf.AppendByte('=')
+}
+
+func (e *logfmtEncoder) appendValue(s string) {
+	if needsQuote(s) {
+		e.buf.AppendString(strconv.Quote(s))
+		return
+	}
+	e.buf.AppendString(s)
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *logfmtEncoder) addJSON(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.addKey(key)
+	e.appendValue(string(b))
+	return nil
+}
+
+func (e *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddArray(key, arr); err != nil {
+		return err
+	}
+	return e.addJSON(key, m.Fields[key])
+}
+
+func (e *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	prefix := e.prefix
+	e.prefix = prefix + key + "."
+	err := obj.MarshalLogObject(e)
+	e.prefix = prefix
+	return err
+}
+
+func (e *logfmtEncoder) AddReflected(key string, v interface{}) error {
+	return e.addJSON(key, v)
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+func (e *logfmtEncoder) AddBinary(key string, v []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, v []byte) { e.AddString(key, string(v)) }
+
+func (e *logfmtEncoder) AddBool(key string, v bool) {
+	e.addKey(key)
+	e.buf.AppendBool(v)
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, v complex128) { e.AddString(key, fmt.Sprint(v)) }
+func (e *logfmtEncoder) AddComplex64(key string, v complex64)   { e.AddString(key, fmt.Sprint(v)) }
+
+func (e *logfmtEncoder) AddDuration(key string, v time.Duration) {
+	if e.cfg.EncodeDuration == nil {
+		e.AddString(key, v.String())
+		return
+	}
+	e.AddString(key, encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeDuration(v, enc) }))
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, v float64) {
+	e.addKey(key)
+	e.buf.AppendFloat(v, 64)
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, v float32) {
+	e.addKey(key)
+	e.buf.AppendFloat(float64(v), 32)
+}
+
+func (e *logfmtEncoder) AddInt(key string, v int)     { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt32(key string, v int32) { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt16(key string, v int16) { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt8(key string, v int8)   { e.AddInt64(key, int64(v)) }
+
+func (e *logfmtEncoder) AddInt64(key string, v int64) {
+	e.addKey(key)
+	e.buf.AppendInt(v)
+}
+
+func (e *logfmtEncoder) AddString(key, v string) {
+	e.addKey(key)
+	e.appendValue(v)
+}
+
+func (e *logfmtEncoder) AddTime(key string, v time.Time) {
+	if e.cfg.EncodeTime == nil {
+		e.AddString(key, v.Format(time.RFC3339Nano))
+		return
+	}
+	e.AddString(key, encodePrimitive(func(enc zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeTime(v, enc) }))
+}
+
+func (e *logfmtEncoder) AddUint(key string, v uint)       { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint32(key string, v uint32)   { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint16(key string, v uint16)   { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint8(key string, v uint8)     { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUintptr(key string, v uintptr) { e.AddUint64(key, uint64(v)) }
+
+func (e *logfmtEncoder) AddUint64(key string, v uint64) {
+	e.addKey(key)
+	e.buf.AppendUint(v)
+}
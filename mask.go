@@ -0,0 +1,48 @@
+package prettyZap
+
+import (
+	"regexp"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var maskReplacement = []byte("***")
+
+// compileMasks 编译 MaskPatterns，无效的正则输出警告后跳过
+func compileMasks(patterns []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			warnf("ignore mask pattern %q: %v", p, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+// maskSyncer 在写出前将编码后日志中匹配 res 的内容替换为 ***，作为敏感信息的最后一道防线
+type maskSyncer struct {
+	zapcore.WriteSyncer
+	res []*regexp.Regexp
+}
+
+func maskOutput(ws zapcore.WriteSyncer, res []*regexp.Regexp) zapcore.WriteSyncer {
+	if len(res) == 0 {
+		return ws
+	}
+	return maskSyncer{WriteSyncer: ws, res: res}
+}
+
+func (s maskSyncer) Write(p []byte) (int, error) {
+	out := p
+	for _, re := range s.res {
+		out = re.ReplaceAll(out, maskReplacement)
+	}
+	if _, err := s.WriteSyncer.Write(out); err != nil {
+		return 0, err
+	}
+	// 替换后长度会变化，按调用方传入的长度返回
+	return len(p), nil
+}
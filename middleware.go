@@ -0,0 +1,233 @@
+package prettyZap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// HTTPMiddleware 为每个请求记录一条日志：方法、路径、状态码、耗时和响应大小。
+// HTTPBodyLimit 大于 0 时同时记录请求体（处理函数读取到的部分）和响应体的前 HTTPBodyLimit 字节，
+// RedactKeys 中的字段会被隐藏。处理函数 panic 时以 error 级别记录 panic 值和堆栈，
+// 未写出响应头时返回 500；HTTPRepanic 为 true 时记录后继续 panic
+func HTTPMiddleware(next http.Handler) http.Handler {
+	limit := DefaultCfg.HTTPBodyLimit
+	repanic := DefaultCfg.HTTPRepanic
+	redact := newRedactor(DefaultCfg.RedactKeys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		var reqBody *limitedBuffer
+		if limit > 0 && r.Body != nil {
+			reqBody = &limitedBuffer{limit: limit}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, reqBody), r.Body}
+		}
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		if limit > 0 {
+			rw.body = &limitedBuffer{limit: limit}
+		}
+		fields := func() []zap.Field {
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", rw.status),
+				zap.Duration("duration", time.Since(start)),
+				zap.Int64("bytes", rw.size),
+			}
+			if reqBody != nil {
+				fields = append(fields, zap.String("requestBody", redact(reqBody.String())))
+			}
+			if rw.body != nil {
+				fields = append(fields, zap.String("responseBody", redact(rw.body.String())))
+			}
+			return fields
+		}
+		// caller 指向中间件本身没有意义；经过 CorrelationMiddleware 时附带 correlation_id
+		log := FromContext(r.Context()).Desugar().WithOptions(zap.WithCaller(false))
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				// 处理函数主动中止响应，net/http 不记录，这里同样不记录
+				panic(p)
+			}
+			if !repanic && !rw.wroteHeader {
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			if ce := log.Check(zapcore.ErrorLevel, "http handler panic"); ce != nil {
+				// 在 recover 处取堆栈，包含 panic 发生的位置
+				ce.Stack = zap.StackSkip("", 1).String
+				ce.Write(append(fields(), zap.Any("panic", p))...)
+			}
+			if repanic {
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		log.Info("http request", fields()...)
+	})
+}
+
+// CorrelationMiddleware 从请求头 CorrelationHeader（默认 X-Correlation-ID）取关联 ID，没有或不合法时生成 UUID，
+// 写入响应头，并通过 Scope 存入 context：FromContext、ScopeFromContext 得到的 logger 都附带 correlation_id。
+// 放在 HTTPMiddleware 外层时请求日志同样附带该字段
+func CorrelationMiddleware(next http.Handler) http.Handler {
+	header := DefaultCfg.CorrelationHeader
+	if header == "" {
+		header = DefaultCorrelationHeader
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if !validCorrelationID(id) {
+			id = newUUID()
+		}
+		w.Header().Set(header, id)
+		ctx := ScopeFromContext(r.Context()).With(zap.String("correlation_id", id)).Context(r.Context())
+		ctx = context.WithValue(ctx, correlationKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type correlationKey struct{}
+
+// CorrelationID 返回 CorrelationMiddleware 存入 context 的关联 ID，不存在时返回空字符串
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// validCorrelationID 只接受长度有限的字母、数字和 -_.:，避免客户端借请求头向日志注入内容
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID 生成随机的 UUID v4
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// responseRecorder 记录状态码、响应大小，以及响应体的前若干字节
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+	body        *limitedBuffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	if w.body != nil {
+		w.body.Write(p[:n])
+	}
+	return n, err
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 转发给底层的 ResponseWriter，websocket 等协议升级需要接管连接
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", w.ResponseWriter)
+	}
+	return h.Hijack()
+}
+
+// Push 转发给底层的 ResponseWriter，不支持 HTTP/2 推送时返回 http.ErrNotSupported
+func (w *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// limitedBuffer 只保留前 limit 字节，超出的部分丢弃并在输出时标记
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + truncatedSuffix
+	}
+	return b.buf.String()
+}
+
+// newRedactor 返回隐藏 keys 对应值的函数，支持 JSON（"key": value）和表单（key=value）两种形式，
+// 对被截断的正文同样有效
+func newRedactor(keys []string) func(string) string {
+	if len(keys) == 0 {
+		return func(s string) string { return s }
+	}
+	var res []*regexp.Regexp
+	for _, k := range keys {
+		k = regexp.QuoteMeta(k)
+		res = append(res,
+			regexp.MustCompile(`(?i)("`+k+`"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`),
+			regexp.MustCompile(`(?i)((?:^|[?&])`+k+`=)[^&\s]*`))
+	}
+	return func(s string) string {
+		for i, re := range res {
+			if i%2 == 0 {
+				s = re.ReplaceAllString(s, `${1}"***"`)
+			} else {
+				s = re.ReplaceAllString(s, `${1}***`)
+			}
+		}
+		return s
+	}
+}
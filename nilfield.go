@@ -0,0 +1,47 @@
+package prettyZap
+
+import (
+	"reflect"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// nil 字段的输出方式，设置到 NilFields
+const (
+	NilAsNull  = iota // 输出 null（zap 默认）
+	NilOmit           // 不输出该字段
+	NilAsEmpty        // 输出空字符串
+)
+
+// newNilCore 按 mode 改写值为 nil 的字段，如 zap.Any("k", nil)、zap.Stringp("k", nil)
+func newNilCore(core zapcore.Core, mode int) zapcore.Core {
+	return &rewriteCore{
+		Core: core,
+		field: func(f zapcore.Field, out []zapcore.Field) []zapcore.Field {
+			if !isNilField(f) {
+				return append(out, f)
+			}
+			if mode == NilAsEmpty {
+				return append(out, zap.String(f.Key, ""))
+			}
+			return out
+		},
+	}
+}
+
+func isNilField(f zapcore.Field) bool {
+	// 只有反射编码的字段会输出 null，zap.Strings(nil) 等数组字段输出 []
+	if f.Type != zapcore.ReflectType {
+		return false
+	}
+	if f.Interface == nil {
+		return true
+	}
+	v := reflect.ValueOf(f.Interface)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package prettyZap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// writePIDFile 将当前进程号写入 path，失败时只输出警告，不影响日志初始化
+func writePIDFile(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		warnf("write pid file %s failed: %v", path, err)
+		return
+	}
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		warnf("write pid file %s failed: %v", path, err)
+	}
+}
+
+// removePIDFile 删除 path。文件中的进程号不是当前进程时保留，避免删掉新进程写入的文件
+func removePIDFile(path string) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(b))); err != nil || pid != os.Getpid() {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		warnf("remove pid file %s failed: %v", path, err)
+	}
+}
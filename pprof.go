@@ -0,0 +1,89 @@
+package prettyZap
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pprofPrefix 与 net/http/pprof 的路径一致，go tool pprof 可以直接使用。
+// 不引入 net/http/pprof，它在 init 时无条件向 http.DefaultServeMux 注册，无法由 EnablePprof 控制
+const pprofPrefix = "/debug/pprof/"
+
+// pprofHandler 提供 CPU profile（profile）、执行追踪（trace）和 runtime/pprof 中的各项 profile，
+// 如 heap、goroutine、block，路径为空时列出可用的 profile
+func pprofHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, pprofPrefix)
+	switch name {
+	case "":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "profile")
+		fmt.Fprintln(w, "trace")
+		for _, p := range pprof.Profiles() {
+			fmt.Fprintln(w, p.Name())
+		}
+	case "profile":
+		profileFor(w, r, 30, func(w http.ResponseWriter, d time.Duration) error {
+			if err := pprof.StartCPUProfile(w); err != nil {
+				return err
+			}
+			time.Sleep(d)
+			pprof.StopCPUProfile()
+			return nil
+		})
+	case "trace":
+		profileFor(w, r, 1, func(w http.ResponseWriter, d time.Duration) error {
+			if err := trace.Start(w); err != nil {
+				return err
+			}
+			time.Sleep(d)
+			trace.Stop()
+			return nil
+		})
+	default:
+		p := pprof.Lookup(name)
+		if p == nil {
+			http.Error(w, "unknown profile "+name, http.StatusNotFound)
+			return
+		}
+		debug, _ := strconv.Atoi(r.FormValue("debug"))
+		if name == "heap" && r.FormValue("gc") != "" {
+			runtime.GC()
+		}
+		if debug > 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		p.WriteTo(w, debug)
+	}
+}
+
+// profileFor 按 seconds 参数采集，时长需小于服务的 WriteTimeout，否则响应会被截断。
+// 未指定时默认 def 秒，但不超过 WriteTimeout 减一秒
+func profileFor(w http.ResponseWriter, r *http.Request, def int, run func(http.ResponseWriter, time.Duration) error) {
+	limit := secondsOr(DefaultCfg.HTTPWriteTimeoutSec, DefaultHTTPTimeoutSec)
+	d := time.Duration(def) * time.Second
+	if d >= limit {
+		d = limit - time.Second
+	}
+	if sec, err := strconv.Atoi(r.FormValue("seconds")); err == nil && sec > 0 {
+		d = time.Duration(sec) * time.Second
+	}
+	if d <= 0 || d >= limit {
+		http.Error(w, fmt.Sprintf("seconds must be less than the server write timeout %v, see HTTPWriteTimeoutSec", limit), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := run(w, d); err != nil {
+		// 同一时间只能有一个 CPU profile 或 trace
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,26 @@
+package prettyZap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+var appPprof sync.Once
+
+func TestServeMuxWithAppPprof(t *testing.T) {
+	// 模拟应用引入 net/http/pprof 后在默认 ServeMux 上注册的路径，-count 重复运行时只注册一次
+	appPprof.Do(func() {
+		http.HandleFunc(pprofPrefix, func(http.ResponseWriter, *http.Request) {})
+	})
+	cfg := DefaultCfg
+	cfg.EnablePprof = true
+	mux := newServeMux(&cfg)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", pprofPrefix+"heap?debug=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET %sheap = %d, want 200", pprofPrefix, rec.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package prettyZap
+
+import (
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	PresetECS     = "ecs"     // Elastic Common Schema
+	PresetGCP     = "gcp"     // Google Cloud Logging
+	PresetDatadog = "datadog" // Datadog 日志，FromContext 附带 dd.trace_id、dd.span_id 用于关联追踪
+)
+
+// preset 描述一组面向特定日志平台的编码器配置和全局字段
+type preset struct {
+	encoder      func(*zapcore.EncoderConfig)
+	serviceField func(name string) zap.Field
+	fields       []zap.Field
+	traceFields  func(traceID, spanID string) []zap.Field // FromContext 附带的追踪字段，nil 时为 trace_id、span_id
+}
+
+var presets = map[string]preset{
+	PresetECS: {
+		encoder: func(c *zapcore.EncoderConfig) {
+			c.TimeKey = "@timestamp"
+			c.LevelKey = "log.level"
+			c.MessageKey = "message"
+			c.NameKey = "log.logger"
+			c.CallerKey = "log.origin.file.name"
+			c.StacktraceKey = "error.stack_trace"
+		},
+		serviceField: func(name string) zap.Field { return zap.String("service.name", name) },
+		fields:       []zap.Field{zap.String("ecs.version", "1.6.0")},
+	},
+	PresetGCP: {
+		encoder: func(c *zapcore.EncoderConfig) {
+			c.TimeKey = "timestamp"
+			c.LevelKey = "severity"
+			c.MessageKey = "message"
+			c.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+			c.EncodeLevel = gcpSeverityEncoder
+		},
+		// Error Reporting 通过 serviceContext.service 识别服务
+		serviceField: func(name string) zap.Field {
+			return zap.Object("serviceContext", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("service", name)
+				return nil
+			}))
+		},
+	},
+	PresetDatadog: {
+		encoder: func(c *zapcore.EncoderConfig) {
+			c.TimeKey = "timestamp"
+			c.LevelKey = "status"
+			c.MessageKey = "message"
+			c.NameKey = "logger.name"
+			c.StacktraceKey = "error.stack"
+		},
+		serviceField: func(name string) zap.Field { return zap.String("service", name) },
+		traceFields: func(traceID, spanID string) []zap.Field {
+			return []zap.Field{zap.String("dd.trace_id", datadogID(traceID)), zap.String("dd.span_id", datadogID(spanID))}
+		},
+	},
+}
+
+// datadogID 将 OpenTelemetry 的十六进制 ID 转为 Datadog 使用的十进制，128 位的 trace ID 取低 64 位；
+// 已是十进制或无法解析时原样返回
+func datadogID(id string) string {
+	if len(id) != 16 && len(id) != 32 {
+		return id
+	}
+	v, err := strconv.ParseUint(id[len(id)-16:], 16, 64)
+	if err != nil {
+		return id
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// traceFields 按预设生成追踪字段
+func traceFields(cfg *PreSetConfig, traceID, spanID string) []zap.Field {
+	if p, ok := presets[cfg.Preset]; ok && p.traceFields != nil {
+		return p.traceFields(traceID, spanID)
+	}
+	return []zap.Field{zap.String("trace_id", traceID), zap.String("span_id", spanID)}
+}
+
+var gcpSeverities = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "DEBUG",
+	zapcore.InfoLevel:   "INFO",
+	zapcore.WarnLevel:   "WARNING",
+	zapcore.ErrorLevel:  "ERROR",
+	zapcore.DPanicLevel: "CRITICAL",
+	zapcore.PanicLevel:  "ALERT",
+	zapcore.FatalLevel:  "EMERGENCY",
+}
+
+func gcpSeverityEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if s, ok := gcpSeverities[l]; ok {
+		enc.AppendString(s)
+		return
+	}
+	enc.AppendString("DEFAULT")
+}
+
+func serviceField(cfg *PreSetConfig) zap.Field {
+	if p, ok := presets[cfg.Preset]; ok && p.serviceField != nil {
+		return p.serviceField(cfg.SvcName)
+	}
+	return zap.String("serviceName", cfg.SvcName)
+}
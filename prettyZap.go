@@ -1,11 +1,19 @@
-package github.com/log/prettyZap
+package prettyZap
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -13,20 +21,41 @@ import (
 )
 
 const (
-	DefaultPort         = "9090"
-	DefaultLevel        = "info"
-	DefaultURL          = "/change/level"
-	DefaultMaxLogSizeMb = 256
-	DefaultMaxBackup    = 10
-	DefaultMaxAgeDay    = 7
-	DefaultSvcName      = "app"
-	IsCompress          = false
+	DefaultPort              = "9090"
+	DefaultLevel             = "info"
+	DefaultURL               = "/change/level"
+	DefaultMaxLogSizeMb      = 256
+	DefaultMaxBackup         = 10
+	DefaultMaxAgeDay         = 7
+	DefaultSvcName           = "app"
+	EnvSvcName               = "SERVICE_NAME"
+	DefaultGzipFlushSec      = 5
+	DefaultRemoteBuffer      = 1024
+	DefaultHealthURL         = "/healthz"
+	DefaultLevelPollSec      = 5
+	DefaultRouteMaxFiles     = 64
+	DefaultRouteIdleSec      = 300
+	DefaultHTTPTimeoutSec    = 5
+	DefaultHTTPIdleSec       = 60
+	DefaultJanitorSec        = 60
+	DefaultCorrelationHeader = "X-Correlation-ID"
+	IsCompress               = false
 )
 
 const (
 	LogOutputStdout        = iota // 0
 	LogOutputFile                 // 1
 	LogOutputStdoutAndFile        // 2
+	LogOutputFileAndStderr        // 3 文件记录全部日志，error 及以上同时输出到 stderr
+)
+
+// 输出目标，可按位组合后设置到 OutputTargets，如 OutputStdout | OutputFile | OutputRemote
+const (
+	OutputStdout = 1 << iota
+	OutputStderr
+	OutputFile
+	OutputRemote
+	OutputEventLog // Windows 事件日志，事件源为 EventLogSource
 )
 
 type PreSetConfig struct {
@@ -37,14 +66,153 @@ type PreSetConfig struct {
 	MaxLogSizeMb int
 	MaxBackup    int
 	MaxAgeDay    int
-	SvcName      string
+	SvcName      string // 服务名，默认取环境变量 SERVICE_NAME，其次取可执行文件名，go run 时取模块名，建议显式设置
 	IsCompress   bool
 	LogOutputTo  int
+	EnableSeq    bool // 每条日志附带递增的 seq 字段
+	NoRotation   bool // 不轮转，直接以追加模式写单个文件
+	// 不输出堆栈。zap.Development() 本身不附加堆栈，这里清空 StacktraceKey，
+	// 确保即使条目带有堆栈也不会写出
+	DisableStacktrace bool
+	// 在 dpanic/panic/fatal 日志写出之后、进程 panic 或退出之前同步调用，可用于清理资源
+	OnFatalHook func(zapcore.Entry)
+	// 每条日志必须出现的 key，缺失时补空字符串，如 []string{"time", "level", "msg", "caller", "serviceName"}
+	RequiredKeys []string
+	// 当前日志文件直接以 gzip 流写入（不轮转，追加为新的 gzip member），每 GzipFlushSec 秒刷新一次，Shutdown 或 Drain 时写入 gzip 结尾
+	CompressStream bool
+	// 日志文件整体写成一个 JSON 数组而不是逐行 JSON，Shutdown 或 Drain 时写入结尾的 "]"。
+	// 需要 json 编码，不轮转，优先于 CompressStream
+	JSONArrayFile bool
+	GzipFlushSec  int
+	Preset        string // 预设的输出格式，如 PresetECS
+	// 从 context 中取出追踪 ID，如 OpenTelemetry 的 span context，非 nil 时 FromContext 附带追踪字段，
+	// 字段名由 Preset 决定，默认为 trace_id、span_id；返回空 traceID 时不附带
+	TraceFromContext func(ctx context.Context) (traceID, spanID string)
+	// 额外发送到远程地址，如 "tcp://127.0.0.1:5170"；缓冲区满时按 RemoteDropPolicy 丢弃
+	RemoteAddr       string
+	RemoteBufferSize int
+	RemoteDropPolicy int
+	LogConfigOnStart bool // 初始化后以 info 级别输出一条生效配置，敏感信息会被隐藏
+	// 采样：每秒内相同消息先输出 SampleInitial 条，之后每 SampleThereafter 条输出一条；
+	// 仅作用于不高于 SampleMaxLevel（默认 info）的日志，SampleInitial 为 0 时不采样
+	SampleInitial    int
+	SampleThereafter int
+	SampleMaxLevel   string
+	SampleFields     []string // 非空时按消息和这些字段的值分别计数采样，如 tenant_id，互不挤占
+	SampleWarmupSec  int      // logger 创建后的这段时间内不采样，保留启动阶段的完整日志
+	OpenRetries      int      // 打开日志文件失败时的最大重试次数，间隔指数退避，全部失败后改为输出到 stdout
+	Version          string   // 构建版本，非空时每条日志附带 version 字段
+	LevelCase        string   // level 字段的大小写："lower"（默认）、"upper"、"capital"；Preset 为 gcp 时不生效
+	Encoding         string   // 输出格式：EncodingJSON（默认）、EncodingCSV、EncodingLogfmt
+	// CSV 的列，可以是 time、level、msg 等 key 或字段名，默认为 time、level、msg
+	CSVColumns []string
+	// 输出目标组合，非 0 时取代 LogOutputTo；此时远程输出需要显式包含 OutputRemote
+	OutputTargets int
+	MaxFieldLen   int    // 消息和字符串字段的最大字节数，超出部分截断并追加 "..."，0 表示不限制
+	LineEnding    string // 行尾，默认 "\n"，Windows 下的日志查看工具可设为 "\r\n"
+	// 控制台输出单独使用的行尾，为空时与 LineEnding 相同；设为 "\n\n" 时条目之间空一行，文件仍保持紧凑
+	ConsoleLineEnding string
+	EnableHealth      bool   // 在 HttpPort 上提供健康检查接口，返回当前级别、输出目标和文件写入状态
+	HealthURL         string // 健康检查路径，默认 DefaultHealthURL
+	// 在 HttpPort 上提供 /debug/pprof/，没有鉴权，只应在内网开启；CPU profile 的时长受 HTTPWriteTimeoutSec 限制
+	EnablePprof bool
+	SyncOnError bool // error 及以上的日志写出后立即 Sync，保证崩溃前的关键日志落盘
+	// 自定义的文件输出，非 nil 时取代内置的 lumberjack 轮转，LogFilePath 及轮转相关配置不再生效。
+	// 实现了 Sync() error 时会被调用；关闭由调用方负责
+	FileWriter io.WriteCloser
+	// 日志文件轮转后在新的 goroutine 中调用，参数为刚关闭的备份文件路径，可用于上传归档。
+	// 开启 IsCompress 时在压缩完成后调用，参数为 .gz 文件；压缩最终失败时为未压缩的文件
+	OnRotate          func(closedFile string)
+	SummaryOnShutdown bool // Shutdown 时输出一条各级别日志条数的汇总
+	// 级别文件，如挂载的 ConfigMap，内容为级别名称或数值；每 LevelPollSec 秒检查一次，变化时修改全局级别
+	LevelFile    string
+	LevelPollSec int
+	// 输出 caller 的最低级别，如 "warn" 时 info、debug 日志不带 caller；为空时所有级别都输出
+	CallerMinLevel string
+	// IsCompress 压缩备份失败时的重试次数，间隔从 1 秒起倍增，最长 1 分钟
+	CompressRetries int
+	// 压缩备份失败时调用，每次失败都会调用；为 nil 时输出到 stderr
+	OnCompressError func(file string, err error)
+	FlattenFields   bool   // 将嵌套对象展开为平铺的 key，如 {"http":{"status":200}} 输出为 "http.status":200
+	FlattenDelim    string // 展开时 key 之间的分隔符，默认 "."
+	AuditFilePath   string // 审计日志文件，由 Audit 写入，每条日志 fsync 后返回，不轮转
+	NilFields       int    // nil 字段的输出方式：NilAsNull（默认）、NilOmit、NilAsEmpty
+	// 每条日志附带 goid 字段（goroutine ID），需要解析栈信息，开销较大，仅用于调试并发问题
+	IncludeGoroutineID bool
+	MaxStackFrames     int // 堆栈最多保留的帧数，0 表示不限制
+	// 额外的日志文件，与主输出接收相同的日志，各自使用自己的格式，轮转配置与主文件相同
+	ExtraFiles []FileSink
+	// Windows 事件日志的事件源名称，默认为 SvcName；事件源需要预先注册
+	EventLogSource string
+	// 正则列表，编码后的日志中匹配的内容被替换为 ***，如信用卡号、身份证号；不作用于 Windows 事件日志
+	MaskPatterns      []string
+	MessagePrefix     bool   // 消息前加上 "[服务名] "，便于在多个服务共用的控制台中区分
+	HTTPBodyLimit     int    // HTTPMiddleware 记录请求体和响应体的最大字节数，0 表示不记录
+	HTTPRepanic       bool   // HTTPMiddleware 记录处理函数的 panic 后继续 panic，而不是返回 500
+	CorrelationHeader string // CorrelationMiddleware 读写关联 ID 的请求头、响应头，默认 DefaultCorrelationHeader
+	// 需要隐藏的字段名（不区分大小写），HTTPMiddleware 记录的正文和日志中同名字段的值都替换为 ***；
+	// 需要比对取值时用 Fingerprint 记录指纹
+	RedactKeys []string
+	Env        string // 运行环境，如 prod、staging、dev，非空时每条日志附带 env 字段
+	// 在该时间窗口内连续重复（级别和消息相同）的日志只输出第一条，随后输出一条重复次数的汇总，0 表示不折叠
+	DedupWindowMs int
+	// 按字段 RouteKey 的值把日志额外写入 RouteDir 下的 <值>.log，如按租户分文件，轮转配置与主文件相同。
+	// 同时打开的文件最多 RouteMaxFiles 个，超过 RouteIdleSec 秒未写入的文件会被关闭
+	RouteKey      string
+	RouteDir      string // 默认为 LogFilePath 所在目录
+	RouteMaxFiles int
+	RouteIdleSec  int
+	// HTTP 服务的读、写、空闲超时（秒），防止慢速连接耗尽资源；0 时使用默认值
+	HTTPReadTimeoutSec  int
+	HTTPWriteTimeoutSec int
+	HTTPIdleTimeoutSec  int
+	// 返回 false 的日志不写出，可以根据级别和字段（包括 With 绑定的字段）决定，如 SampleKey
+	LogFilter func(ent zapcore.Entry, fields []zapcore.Field) bool
+	// statsd 地址（host:port），非空时每秒发送各级别日志条数的计数器 log.<level>
+	StatsdAddr string
+	// 大于 0 时 error 以下的日志先暂存，只保留最近 QuietUntilError 条，出现 error 时连同暂存的日志一起输出。
+	// 只有达到 LogLevel 的日志才会被暂存
+	QuietUntilError int
+	// 非空时在 time 之外再输出一个以此为 key 的毫秒时间戳字段，如 "ts"，便于按数值排序
+	EpochTimeKey string
+	SingleLine   bool // console 和 CSV 格式下转义消息、堆栈（CSV 还包括字段）中的换行，保证每条日志只占一行
+	// console 格式输出到 stdout、stderr 时为 level 着色，文件等其他输出不受影响。
+	// 输出不是终端（管道、重定向）时不着色，除非设置 ForceColor
+	ColorLevel bool
+	ForceColor bool
+	// 日志目录（LogFilePath、ExtraFiles 所在目录）的总大小上限，单位 M，每分钟检查一次，
+	// 超出时从最旧的轮转备份开始删除；0 表示不限制
+	MaxLogDirMb int
+	// 大于 0 时控制台和文件输出改为异步，队列长度为 AsyncBufferSize；队列满时的处理见 AsyncFullPolicy
+	AsyncBufferSize int
+	AsyncFullPolicy int    // AsyncDropAndWarn（默认）、AsyncDrop、AsyncBlock
+	PIDFile         string // 非空时 InitPrettyZap 将进程号写入该文件，Shutdown 时删除；写入失败只输出警告
+	// 调试用，检查 JSON 格式的输出每行是否为合法 JSON：ValidateJSONOff（默认）、ValidateJSONWarn、ValidateJSONPanic
+	ValidateJSON int
+	// 覆盖 ColorLevel 的默认配色，key 为级别名，值为颜色名或 ANSI 代码，如 {"warn": "yellow", "error": "red bold"}
+	LevelColors map[string]string
+
+	colorLevel bool // 由 newCore 为控制台输出设置
+}
+
+// FileSink 是一个额外的日志文件输出
+type FileSink struct {
+	Path     string
+	Encoding string // 同 PreSetConfig.Encoding，如迁移期间同时输出 EncodingJSON 和 EncodingConsole
 }
 
 var zapLogger *zap.SugaredLogger
+
+// plainLogger 是与 zapLogger 对应的非 sugar logger，供 InfoFields 等快速路径使用，两者通过 setLogger 同时设置
+var plainLogger *zap.Logger
 var atomicLevel = zap.NewAtomicLevel()
 
+// rootCore 是 InitPrettyZap 构建的、未做级别过滤的 core，Named 创建的子 logger 共享它
+var rootCore zapcore.Core
+
+// initTime 是 InitPrettyZap 的调用时间，未调用时为进程启动时间，SampleWarmupSec 从此时起算
+var initTime = time.Now()
+
 var levelMap = map[string]zapcore.Level{
 	"debug":  zapcore.DebugLevel,
 	"info":   zapcore.InfoLevel,
@@ -55,17 +223,27 @@ var levelMap = map[string]zapcore.Level{
 	"fatal":  zapcore.FatalLevel,
 }
 
+var levelEncoders = map[string]zapcore.LevelEncoder{
+	"lower":   zapcore.LowercaseLevelEncoder, // info
+	"upper":   zapcore.CapitalLevelEncoder,   // INFO
+	"capital": capitalizedLevelEncoder,       // Info
+}
+
 var DefaultCfg = PreSetConfig{
-	LogFilePath:  getFilePath(),
-	HttpPort:     DefaultPort,
-	LogLevel:     DefaultLevel,
-	RestURL:      DefaultURL,
-	MaxLogSizeMb: DefaultMaxLogSizeMb,
-	MaxBackup:    DefaultMaxBackup,
-	MaxAgeDay:    DefaultMaxAgeDay,
-	SvcName:      getAppname(),
-	IsCompress:   IsCompress,
-	LogOutputTo:  LogOutputStdoutAndFile,
+	LogFilePath:      getFilePath(),
+	HttpPort:         DefaultPort,
+	LogLevel:         DefaultLevel,
+	RestURL:          DefaultURL,
+	MaxLogSizeMb:     DefaultMaxLogSizeMb,
+	MaxBackup:        DefaultMaxBackup,
+	MaxAgeDay:        DefaultMaxAgeDay,
+	SvcName:          defaultSvcName(),
+	IsCompress:       IsCompress,
+	LogOutputTo:      LogOutputStdoutAndFile,
+	GzipFlushSec:     DefaultGzipFlushSec,
+	RemoteBufferSize: DefaultRemoteBuffer,
+	HealthURL:        DefaultHealthURL,
+	LevelPollSec:     DefaultLevelPollSec,
 }
 
 var encoderConfig = zapcore.EncoderConfig{
@@ -84,30 +262,161 @@ var encoderConfig = zapcore.EncoderConfig{
 	EncodeName: zapcore.FullNameEncoder,
 }
 
+func newEncoderConfig(cfg *PreSetConfig) zapcore.EncoderConfig {
+	encCfg := encoderConfig
+	// LevelCase 先于 Preset 应用，gcp 等平台要求的 severity 取值不被 LevelCase 覆盖
+	if enc, ok := levelEncoders[cfg.LevelCase]; ok {
+		encCfg.EncodeLevel = enc
+	}
+	if p, ok := presets[cfg.Preset]; ok {
+		p.encoder(&encCfg)
+	}
+	if cfg.colorLevel {
+		encCfg.EncodeLevel = colorLevelEncoder(encCfg.EncodeLevel, levelColors(cfg.LevelColors))
+	}
+	if cfg.LineEnding != "" {
+		encCfg.LineEnding = cfg.LineEnding
+	}
+	if cfg.DisableStacktrace {
+		encCfg.StacktraceKey = ""
+	}
+	return encCfg
+}
+
+func capitalizedLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	s := l.String()
+	enc.AppendString(strings.ToUpper(s[:1]) + s[1:])
+}
+
+// getLoggerLevel 无法解析时默认为 info，需要严格校验时使用 ParseLevel
 func getLoggerLevel(lvl string) zapcore.Level {
-	if level, ok := levelMap[lvl]; ok {
+	if level, err := ParseLevel(lvl); err == nil {
 		return level
 	}
 	return zapcore.InfoLevel
 }
 
 func InitPrettyZap(preCfg *PreSetConfig) {
+	initTime = time.Now()
 	transferCfg(preCfg, &DefaultCfg)
-	http.HandleFunc(DefaultCfg.RestURL, atomicLevel.ServeHTTP)
+	server := &http.Server{
+		Addr:         ":" + DefaultCfg.HttpPort,
+		Handler:      newServeMux(&DefaultCfg),
+		ReadTimeout:  secondsOr(DefaultCfg.HTTPReadTimeoutSec, DefaultHTTPTimeoutSec),
+		WriteTimeout: secondsOr(DefaultCfg.HTTPWriteTimeoutSec, DefaultHTTPTimeoutSec),
+		IdleTimeout:  secondsOr(DefaultCfg.HTTPIdleTimeoutSec, DefaultHTTPIdleSec),
+	}
+	atomicLevel.SetLevel(getLoggerLevel(DefaultCfg.LogLevel))
+	if DefaultCfg.LevelFile != "" {
+		interval := time.Duration(DefaultCfg.LevelPollSec) * time.Second
+		if interval <= 0 {
+			interval = DefaultLevelPollSec * time.Second
+		}
+		// 文件中的级别覆盖 LogLevel
+		watchLevelFile(DefaultCfg.LevelFile, interval)
+	}
+	outputs := openOutputs(&DefaultCfg)
+	sinks = newSinkSet(buildCore(&DefaultCfg, outputs, DefaultCfg.Encoding))
+	sinks.outputs = outputs
+	rootCore = wrapRootCore(&sinkCore{set: sinks}, &DefaultCfg)
+	if DefaultCfg.AuditFilePath != "" {
+		core, err := newAuditCore(&DefaultCfg)
+		if err != nil {
+			// 审计日志无法降级输出到其他位置，直接失败
+			panic(err)
+		}
+		auditCore = core
+	}
+	if DefaultCfg.PIDFile != "" {
+		writePIDFile(DefaultCfg.PIDFile)
+	}
+	if DefaultCfg.MaxLogDirMb > 0 {
+		startJanitor(&DefaultCfg, DefaultCfg.MaxLogDirMb, DefaultJanitorSec*time.Second)
+	}
+	if DefaultCfg.StatsdAddr != "" {
+		if err := startStatsd(DefaultCfg.StatsdAddr); err != nil {
+			warnf("statsd %s unavailable: %v", DefaultCfg.StatsdAddr, err)
+		}
+	}
+	log := newLogger(rootCore, atomicLevel, &DefaultCfg)
+	// defer log.Sync()
+	setLogger(log)
+	if DefaultCfg.LogConfigOnStart {
+		log.Info("prettyZap initialized", configFields(&DefaultCfg)...)
+	}
+	// logger 就绪后再启动服务，监听失败时先写入日志，保证各输出都能收到
 	go func() {
-		if err := http.ListenAndServe(":"+DefaultCfg.HttpPort, nil); err != nil {
+		if err := server.ListenAndServe(); err != nil {
+			log.WithOptions(zap.AddCallerSkip(-1)).Error("prettyZap http server failed",
+				zap.String("addr", server.Addr), zap.Error(err))
+			log.Sync()
 			panic(err)
 		}
 	}()
-
-	log := NewLogger(&DefaultCfg)
-	// defer log.Sync()
-	zapLogger = log.Sugar()
 	zapLogger.Sync()
 	// SugaredLogger transfer back to Logger object
 	// plain := zapLogger.Desugar()
 }
 
+// newServeMux 注册调整级别、健康检查和 pprof 接口。使用独立的 ServeMux 而不是 http.DefaultServeMux，
+// 应用引入 net/http/pprof 或自行注册同名路径时不会冲突
+func newServeMux(cfg *PreSetConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.RestURL, levelHandler)
+	if cfg.EnableHealth {
+		healthURL := cfg.HealthURL
+		if healthURL == "" {
+			healthURL = DefaultHealthURL
+		}
+		mux.HandleFunc(healthURL, healthHandler)
+	}
+	if cfg.EnablePprof {
+		mux.HandleFunc(pprofPrefix, pprofHandler)
+	}
+	return mux
+}
+
+// secondsOr 将秒数转为 Duration，sec 不大于 0 时使用 def
+func secondsOr(sec, def int) time.Duration {
+	if sec <= 0 {
+		sec = def
+	}
+	return time.Duration(sec) * time.Second
+}
+
+func setLogger(log *zap.Logger) {
+	plainLogger = log
+	zapLogger = log.Sugar()
+}
+
+// configFields 描述生效的配置，远程地址中的账号密码会被隐藏
+func configFields(cfg *PreSetConfig) []zap.Field {
+	return []zap.Field{
+		zap.String("logLevel", cfg.LogLevel),
+		zap.Int("logOutputTo", cfg.LogOutputTo),
+		zap.Int("outputTargets", outputTargets(cfg)),
+		zap.String("logFilePath", cfg.LogFilePath),
+		zap.Int("maxLogSizeMb", cfg.MaxLogSizeMb),
+		zap.Int("maxBackup", cfg.MaxBackup),
+		zap.Int("maxAgeDay", cfg.MaxAgeDay),
+		zap.Bool("isCompress", cfg.IsCompress),
+		zap.Bool("noRotation", cfg.NoRotation),
+		zap.Bool("compressStream", cfg.CompressStream),
+		zap.Bool("jsonArrayFile", cfg.JSONArrayFile),
+		zap.String("httpPort", cfg.HttpPort),
+		zap.String("restURL", cfg.RestURL),
+		zap.String("preset", cfg.Preset),
+		zap.String("remoteAddr", redactURL(cfg.RemoteAddr)),
+	}
+}
+
+func redactURL(addr string) string {
+	if u, err := url.Parse(addr); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return addr
+}
+
 func transferCfg(preConfig, runCfg *PreSetConfig) {
 	if preConfig != nil && runCfg != nil {
 		if runCfg.IsCompress != preConfig.IsCompress {
@@ -119,6 +428,10 @@ func transferCfg(preConfig, runCfg *PreSetConfig) {
 		if runCfg.SvcName != preConfig.SvcName {
 			runCfg.SvcName = preConfig.SvcName
 		}
+		if runCfg.SvcName == "" {
+			// 调用方传入自己的配置时同样按 SERVICE_NAME、可执行文件名取默认值
+			runCfg.SvcName = defaultSvcName()
+		}
 		if runCfg.MaxBackup != preConfig.MaxBackup {
 			runCfg.MaxBackup = preConfig.MaxBackup
 		}
@@ -140,46 +453,606 @@ func transferCfg(preConfig, runCfg *PreSetConfig) {
 		if runCfg.LogOutputTo != preConfig.LogOutputTo {
 			runCfg.LogOutputTo = preConfig.LogOutputTo
 		}
+		if runCfg.EnableSeq != preConfig.EnableSeq {
+			runCfg.EnableSeq = preConfig.EnableSeq
+		}
+		if runCfg.NoRotation != preConfig.NoRotation {
+			runCfg.NoRotation = preConfig.NoRotation
+		}
+		if runCfg.DisableStacktrace != preConfig.DisableStacktrace {
+			runCfg.DisableStacktrace = preConfig.DisableStacktrace
+		}
+		runCfg.OnFatalHook = preConfig.OnFatalHook
+		runCfg.RequiredKeys = preConfig.RequiredKeys
+		if runCfg.CompressStream != preConfig.CompressStream {
+			runCfg.CompressStream = preConfig.CompressStream
+		}
+		if runCfg.JSONArrayFile != preConfig.JSONArrayFile {
+			runCfg.JSONArrayFile = preConfig.JSONArrayFile
+		}
+		if runCfg.GzipFlushSec != preConfig.GzipFlushSec {
+			runCfg.GzipFlushSec = preConfig.GzipFlushSec
+		}
+		if runCfg.Preset != preConfig.Preset {
+			runCfg.Preset = preConfig.Preset
+		}
+		runCfg.TraceFromContext = preConfig.TraceFromContext
+		if runCfg.RemoteAddr != preConfig.RemoteAddr {
+			runCfg.RemoteAddr = preConfig.RemoteAddr
+		}
+		if runCfg.RemoteBufferSize != preConfig.RemoteBufferSize {
+			runCfg.RemoteBufferSize = preConfig.RemoteBufferSize
+		}
+		if runCfg.RemoteDropPolicy != preConfig.RemoteDropPolicy {
+			runCfg.RemoteDropPolicy = preConfig.RemoteDropPolicy
+		}
+		if runCfg.LogConfigOnStart != preConfig.LogConfigOnStart {
+			runCfg.LogConfigOnStart = preConfig.LogConfigOnStart
+		}
+		if runCfg.SampleInitial != preConfig.SampleInitial {
+			runCfg.SampleInitial = preConfig.SampleInitial
+		}
+		if runCfg.SampleThereafter != preConfig.SampleThereafter {
+			runCfg.SampleThereafter = preConfig.SampleThereafter
+		}
+		if runCfg.SampleMaxLevel != preConfig.SampleMaxLevel {
+			runCfg.SampleMaxLevel = preConfig.SampleMaxLevel
+		}
+		runCfg.SampleFields = preConfig.SampleFields
+		if runCfg.SampleWarmupSec != preConfig.SampleWarmupSec {
+			runCfg.SampleWarmupSec = preConfig.SampleWarmupSec
+		}
+		if runCfg.OpenRetries != preConfig.OpenRetries {
+			runCfg.OpenRetries = preConfig.OpenRetries
+		}
+		if runCfg.Version != preConfig.Version {
+			runCfg.Version = preConfig.Version
+		}
+		if runCfg.LevelCase != preConfig.LevelCase {
+			runCfg.LevelCase = preConfig.LevelCase
+		}
+		if runCfg.Encoding != preConfig.Encoding {
+			runCfg.Encoding = preConfig.Encoding
+		}
+		runCfg.CSVColumns = preConfig.CSVColumns
+		if runCfg.OutputTargets != preConfig.OutputTargets {
+			runCfg.OutputTargets = preConfig.OutputTargets
+		}
+		if runCfg.MaxFieldLen != preConfig.MaxFieldLen {
+			runCfg.MaxFieldLen = preConfig.MaxFieldLen
+		}
+		if runCfg.LineEnding != preConfig.LineEnding {
+			runCfg.LineEnding = preConfig.LineEnding
+		}
+		if runCfg.ConsoleLineEnding != preConfig.ConsoleLineEnding {
+			runCfg.ConsoleLineEnding = preConfig.ConsoleLineEnding
+		}
+		if runCfg.EnableHealth != preConfig.EnableHealth {
+			runCfg.EnableHealth = preConfig.EnableHealth
+		}
+		if runCfg.EnablePprof != preConfig.EnablePprof {
+			runCfg.EnablePprof = preConfig.EnablePprof
+		}
+		if runCfg.HealthURL != preConfig.HealthURL {
+			runCfg.HealthURL = preConfig.HealthURL
+		}
+		if runCfg.SyncOnError != preConfig.SyncOnError {
+			runCfg.SyncOnError = preConfig.SyncOnError
+		}
+		runCfg.FileWriter = preConfig.FileWriter
+		runCfg.OnRotate = preConfig.OnRotate
+		if runCfg.CompressRetries != preConfig.CompressRetries {
+			runCfg.CompressRetries = preConfig.CompressRetries
+		}
+		runCfg.OnCompressError = preConfig.OnCompressError
+		if runCfg.SummaryOnShutdown != preConfig.SummaryOnShutdown {
+			runCfg.SummaryOnShutdown = preConfig.SummaryOnShutdown
+		}
+		if runCfg.LevelFile != preConfig.LevelFile {
+			runCfg.LevelFile = preConfig.LevelFile
+		}
+		if runCfg.LevelPollSec != preConfig.LevelPollSec {
+			runCfg.LevelPollSec = preConfig.LevelPollSec
+		}
+		if runCfg.CallerMinLevel != preConfig.CallerMinLevel {
+			runCfg.CallerMinLevel = preConfig.CallerMinLevel
+		}
+		if runCfg.FlattenFields != preConfig.FlattenFields {
+			runCfg.FlattenFields = preConfig.FlattenFields
+		}
+		if runCfg.FlattenDelim != preConfig.FlattenDelim {
+			runCfg.FlattenDelim = preConfig.FlattenDelim
+		}
+		if runCfg.AuditFilePath != preConfig.AuditFilePath {
+			runCfg.AuditFilePath = preConfig.AuditFilePath
+		}
+		if runCfg.NilFields != preConfig.NilFields {
+			runCfg.NilFields = preConfig.NilFields
+		}
+		if runCfg.IncludeGoroutineID != preConfig.IncludeGoroutineID {
+			runCfg.IncludeGoroutineID = preConfig.IncludeGoroutineID
+		}
+		if runCfg.MaxStackFrames != preConfig.MaxStackFrames {
+			runCfg.MaxStackFrames = preConfig.MaxStackFrames
+		}
+		runCfg.ExtraFiles = preConfig.ExtraFiles
+		if runCfg.EventLogSource != preConfig.EventLogSource {
+			runCfg.EventLogSource = preConfig.EventLogSource
+		}
+		runCfg.MaskPatterns = preConfig.MaskPatterns
+		runCfg.LevelColors = preConfig.LevelColors
+		if runCfg.MessagePrefix != preConfig.MessagePrefix {
+			runCfg.MessagePrefix = preConfig.MessagePrefix
+		}
+		if runCfg.HTTPBodyLimit != preConfig.HTTPBodyLimit {
+			runCfg.HTTPBodyLimit = preConfig.HTTPBodyLimit
+		}
+		if runCfg.HTTPRepanic != preConfig.HTTPRepanic {
+			runCfg.HTTPRepanic = preConfig.HTTPRepanic
+		}
+		if runCfg.CorrelationHeader != preConfig.CorrelationHeader {
+			runCfg.CorrelationHeader = preConfig.CorrelationHeader
+		}
+		runCfg.RedactKeys = preConfig.RedactKeys
+		if runCfg.Env != preConfig.Env {
+			runCfg.Env = preConfig.Env
+		}
+		if runCfg.DedupWindowMs != preConfig.DedupWindowMs {
+			runCfg.DedupWindowMs = preConfig.DedupWindowMs
+		}
+		if runCfg.RouteKey != preConfig.RouteKey {
+			runCfg.RouteKey = preConfig.RouteKey
+		}
+		if runCfg.RouteDir != preConfig.RouteDir {
+			runCfg.RouteDir = preConfig.RouteDir
+		}
+		if runCfg.RouteMaxFiles != preConfig.RouteMaxFiles {
+			runCfg.RouteMaxFiles = preConfig.RouteMaxFiles
+		}
+		if runCfg.RouteIdleSec != preConfig.RouteIdleSec {
+			runCfg.RouteIdleSec = preConfig.RouteIdleSec
+		}
+		if runCfg.HTTPReadTimeoutSec != preConfig.HTTPReadTimeoutSec {
+			runCfg.HTTPReadTimeoutSec = preConfig.HTTPReadTimeoutSec
+		}
+		if runCfg.HTTPWriteTimeoutSec != preConfig.HTTPWriteTimeoutSec {
+			runCfg.HTTPWriteTimeoutSec = preConfig.HTTPWriteTimeoutSec
+		}
+		if runCfg.HTTPIdleTimeoutSec != preConfig.HTTPIdleTimeoutSec {
+			runCfg.HTTPIdleTimeoutSec = preConfig.HTTPIdleTimeoutSec
+		}
+		runCfg.LogFilter = preConfig.LogFilter
+		if runCfg.StatsdAddr != preConfig.StatsdAddr {
+			runCfg.StatsdAddr = preConfig.StatsdAddr
+		}
+		if runCfg.SingleLine != preConfig.SingleLine {
+			runCfg.SingleLine = preConfig.SingleLine
+		}
+		if runCfg.QuietUntilError != preConfig.QuietUntilError {
+			runCfg.QuietUntilError = preConfig.QuietUntilError
+		}
+		if runCfg.EpochTimeKey != preConfig.EpochTimeKey {
+			runCfg.EpochTimeKey = preConfig.EpochTimeKey
+		}
+		if runCfg.ColorLevel != preConfig.ColorLevel {
+			runCfg.ColorLevel = preConfig.ColorLevel
+		}
+		if runCfg.ForceColor != preConfig.ForceColor {
+			runCfg.ForceColor = preConfig.ForceColor
+		}
+		if runCfg.MaxLogDirMb != preConfig.MaxLogDirMb {
+			runCfg.MaxLogDirMb = preConfig.MaxLogDirMb
+		}
+		if runCfg.PIDFile != preConfig.PIDFile {
+			runCfg.PIDFile = preConfig.PIDFile
+		}
+		if runCfg.AsyncBufferSize != preConfig.AsyncBufferSize {
+			runCfg.AsyncBufferSize = preConfig.AsyncBufferSize
+		}
+		if runCfg.AsyncFullPolicy != preConfig.AsyncFullPolicy {
+			runCfg.AsyncFullPolicy = preConfig.AsyncFullPolicy
+		}
+		if runCfg.ValidateJSON != preConfig.ValidateJSON {
+			runCfg.ValidateJSON = preConfig.ValidateJSON
+		}
 	}
 }
 
+// NewLogger 构建一个 logger，级别由全局 atomicLevel 控制，可通过 HTTP 接口动态修改
 func NewLogger(cfg *PreSetConfig) *zap.Logger {
-	return zap.New(newCore(cfg),
+	atomicLevel.SetLevel(getLoggerLevel(cfg.LogLevel))
+	return newLogger(newRootCore(cfg), atomicLevel, cfg)
+}
+
+func newLogger(root zapcore.Core, level zapcore.LevelEnabler, cfg *PreSetConfig) *zap.Logger {
+	opts := append(loggerOptions(cfg), zap.WithFatalHook(syncThenExit{root}))
+	return zap.New(newLeveledCore(root, level, cfg), opts...)
+}
+
+// newLeveledCore 在 root core 之上叠加级别过滤和采样
+func newLeveledCore(root zapcore.Core, level zapcore.LevelEnabler, cfg *PreSetConfig) zapcore.Core {
+	core := newLevelCore(root, level)
+	if cfg.SampleInitial > 0 {
+		core = newSamplingCore(core, level, cfg)
+	}
+	return core
+}
+
+// newRootCore 构建输出和字段处理部分的 core，不做级别过滤
+func newRootCore(cfg *PreSetConfig) zapcore.Core {
+	return wrapRootCore(newCore(cfg), cfg)
+}
+
+// wrapRootCore 在输出 core 外叠加字段处理
+func wrapRootCore(core zapcore.Core, cfg *PreSetConfig) zapcore.Core {
+	// FromContext 传入的 deadline 在最内层换算，外层 core 看不到 deadline_remaining_ms
+	core = &deadlineCore{Core: core}
+	if cfg.SyncOnError {
+		// ioCore 只在 error 以上（DPanic 起）自动 Sync，error 本身需要这里处理
+		core = &syncCore{Core: core, level: zapcore.ErrorLevel}
+	}
+	if cfg.EnableSeq {
+		core = &stampCore{Core: core, stamp: nextSeq}
+	}
+	if cfg.IncludeGoroutineID {
+		core = &stampCore{Core: core, stamp: goroutineID}
+	}
+	if cfg.EpochTimeKey != "" {
+		core = &stampCore{Core: core, stamp: epochMillis(cfg.EpochTimeKey)}
+	}
+	if cfg.MaxStackFrames > 0 {
+		core = &entryCore{Core: core, edit: trimStack(cfg.MaxStackFrames)}
+	}
+	if len(cfg.RequiredKeys) > 0 {
+		core = newSchemaCore(core, newEncoderConfig(cfg), cfg.RequiredKeys)
+	}
+	if cfg.SingleLine {
+		core = newSingleLineCore(core, cfg)
+	}
+	if cfg.MessagePrefix {
+		// 在截断之后添加，前缀不会被截掉
+		prefix := "[" + cfg.SvcName + "] "
+		core = &rewriteCore{Core: core, msg: func(msg string) string { return prefix + msg }}
+	}
+	if cfg.MaxFieldLen > 0 {
+		core = newTruncateCore(core, cfg.MaxFieldLen)
+	}
+	if len(cfg.RedactKeys) > 0 {
+		core = &rewriteCore{Core: core, field: redactFields(cfg.RedactKeys)}
+	}
+	if cfg.NilFields != NilAsNull {
+		core = newNilCore(core, cfg.NilFields)
+	}
+	if cfg.FlattenFields {
+		// 在截断之前展开，展开得到的字符串字段同样会被截断
+		core = newFlattenCore(core, cfg.FlattenDelim)
+	}
+	// 放在最外层，RequiredKeys 能看到被去掉的 caller
+	if cfg.CallerMinLevel != "" {
+		if min, err := ParseLevel(cfg.CallerMinLevel); err == nil {
+			core = &entryCore{Core: core, edit: omitCaller(min)}
+		} else {
+			warnf("ignore CallerMinLevel: %v", err)
+		}
+	}
+	if cfg.DedupWindowMs > 0 {
+		// 按原始消息比较，放在改写消息的 core 之外
+		core = newDedupCore(core, time.Duration(cfg.DedupWindowMs)*time.Millisecond)
+	}
+	// 规则由 Throttle 在运行时注册，没有规则时只多一次原子读取
+	core = &throttleCore{Core: core}
+	if cfg.QuietUntilError > 0 {
+		core = newQuietCore(core, cfg.QuietUntilError)
+	}
+	if cfg.LogFilter != nil {
+		core = &filterCore{Core: core, keep: cfg.LogFilter}
+	}
+	return core
+}
+
+func loggerOptions(cfg *PreSetConfig) []zap.Option {
+	opts := []zap.Option{
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 		zap.Development(),
-		zap.Fields(zap.String("serviceName", cfg.SvcName)))
+		zap.Fields(globalFields(cfg)...),
+	}
+	if cfg.OnFatalHook != nil {
+		opts = append(opts, zap.Hooks(fatalHook(cfg.OnFatalHook)))
+	}
+	if cfg.SummaryOnShutdown || cfg.StatsdAddr != "" {
+		opts = append(opts, zap.Hooks(countHook))
+	}
+	return opts
+}
+
+// globalFields 返回附加到每条日志上的字段
+func globalFields(cfg *PreSetConfig) []zap.Field {
+	fields := []zap.Field{serviceField(cfg)}
+	if cfg.Version != "" {
+		fields = append(fields, zap.String("version", cfg.Version))
+	}
+	if cfg.Env != "" {
+		fields = append(fields, zap.String("env", cfg.Env))
+	}
+	if p, ok := presets[cfg.Preset]; ok {
+		fields = append(fields, p.fields...)
+	}
+	return fields
+}
+
+// syncThenExit 在 fatal 日志写出后先同步所有输出（包括带缓冲的输出）再退出进程，
+// 避免 os.Exit 导致 fatal 日志本身和之前缓冲的日志丢失
+type syncThenExit struct {
+	core zapcore.Core
+}
+
+func (h syncThenExit) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	h.core.Sync()
+	os.Exit(1)
+}
+
+// fatalHook 只在会导致 panic 或退出的级别上触发；zap 的 hooks 在条目写出后、
+// panic/os.Exit 之前执行
+func fatalHook(fn func(zapcore.Entry)) func(zapcore.Entry) error {
+	return func(ent zapcore.Entry) error {
+		if ent.Level >= zapcore.DPanicLevel {
+			fn(ent)
+		}
+		return nil
+	}
+}
+
+// outputTargets 返回生效的输出目标组合，未设置 OutputTargets 时由 LogOutputTo 换算
+func outputTargets(cfg *PreSetConfig) int {
+	if cfg.OutputTargets != 0 {
+		return cfg.OutputTargets
+	}
+	var targets int
+	switch cfg.LogOutputTo {
+	case LogOutputStdout:
+		targets = OutputStdout
+	case LogOutputFile, LogOutputFileAndStderr:
+		targets = OutputFile
+	default:
+		targets = OutputStdout | OutputFile
+	}
+	if cfg.RemoteAddr != "" {
+		targets |= OutputRemote
+	}
+	return targets
+}
+
+// outputTo 返回控制台输出和其他输出，两者可以使用不同的行尾
+func outputTo(cfg *PreSetConfig) (console, others []zapcore.WriteSyncer) {
+	targets := outputTargets(cfg)
+	if targets&OutputStdout != 0 {
+		console = append(console, consoleSyncer{os.Stdout})
+	}
+	if targets&OutputStderr != 0 {
+		console = append(console, consoleSyncer{os.Stderr})
+	}
+	if targets&OutputFile != 0 {
+		file, err := fileSyncer(cfg)
+		switch {
+		case err == nil:
+			others = append(others, fileStatusSyncer{file, cfg.LogFilePath})
+		case targets&(OutputStdout|OutputStderr) != 0:
+			warnf("open log file %s failed, log to console only: %v", cfg.LogFilePath, err)
+		default:
+			warnf("open log file %s failed, fall back to stdout: %v", cfg.LogFilePath, err)
+			console = append(console, consoleSyncer{os.Stdout})
+		}
+	}
+	if targets&OutputRemote != 0 && cfg.RemoteAddr != "" {
+		others = append(others, newRemoteSyncer(cfg.RemoteAddr, cfg.RemoteBufferSize, cfg.RemoteDropPolicy))
+	}
+	return console, others
+}
+
+func fileSyncer(cfg *PreSetConfig) (zapcore.WriteSyncer, error) {
+	if cfg.FileWriter != nil {
+		ws := zapcore.AddSync(cfg.FileWriter)
+		if cfg.Encoding == EncodingCSV {
+			return newCSVHeaderSyncer(ws, csvHeader(cfg), nil), nil
+		}
+		return ws, nil
+	}
+	if err := claimFile(cfg.LogFilePath); err != nil {
+		return nil, err
+	}
+	ws, err := openFileSyncer(cfg)
+	if err != nil {
+		releaseFile(cfg.LogFilePath)
+		return nil, err
+	}
+	if c, ok := ws.(io.Closer); ok {
+		registerCloser(cfg.LogFilePath, c)
+	}
+	return ws, nil
 }
 
-func outputTo(cfg *PreSetConfig) []zapcore.WriteSyncer {
-	var multiWriteSyncer []zapcore.WriteSyncer
-	hook := lumberjack.Logger{
+func openFileSyncer(cfg *PreSetConfig) (zapcore.WriteSyncer, error) {
+	if cfg.NoRotation || cfg.CompressStream || cfg.JSONArrayFile {
+		// lumberjack 的 MaxSize 为 0 时取默认 100M，无法真正关闭轮转，这里直接写文件；
+		// gzip 流、JSON 数组被轮转截断后无法解析，所以同样不轮转
+		f, err := openWithRetry(cfg.LogFilePath, cfg.OpenRetries)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.JSONArrayFile {
+			s, err := newJSONArraySyncer(f, cfg.LogFilePath)
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			return s, nil
+		}
+		var ws zapcore.WriteSyncer = f
+		if cfg.CompressStream {
+			ws = newGzipSyncer(f, time.Duration(cfg.GzipFlushSec)*time.Second)
+		}
+		if cfg.Encoding == EncodingCSV {
+			ws = newCSVHeaderSyncer(ws, csvHeader(cfg), fileIsEmpty(f))
+		}
+		return ws, nil
+	}
+	if cfg.OpenRetries > 0 {
+		// lumberjack 在首次写入时才打开文件，这里提前探测，等待挂载较慢的存储就绪
+		f, err := openWithRetry(cfg.LogFilePath, cfg.OpenRetries)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+	}
+	hook := &lumberjack.Logger{
 		Filename:   cfg.LogFilePath,  // 日志文件路径
 		MaxSize:    cfg.MaxLogSizeMb, // 每个日志文件保存的最大尺寸 单位：M
 		MaxBackups: cfg.MaxBackup,    // 日志文件最多保存多少个备份
 		MaxAge:     cfg.MaxAgeDay,    // 文件最多保存多少天
-		Compress:   cfg.IsCompress,   // 是否压缩
 	}
-	switch cfg.LogOutputTo {
-	case LogOutputStdout:
-		multiWriteSyncer = append(multiWriteSyncer, zapcore.AddSync(os.Stdout))
-		break
-	case LogOutputFile:
-		multiWriteSyncer = append(multiWriteSyncer, zapcore.AddSync(&hook))
-		break
-	default:
-		multiWriteSyncer = append(multiWriteSyncer, zapcore.AddSync(os.Stdout), zapcore.AddSync(&hook))
+	onRotate := cfg.OnRotate
+	if cfg.IsCompress {
+		// 不使用 lumberjack 的压缩，由 compressBackups 压缩以便报告错误和重试
+		onRotate = compressBackups(cfg.CompressRetries, cfg.OnCompressError, cfg.OnRotate)
+	}
+	var header []byte
+	if cfg.Encoding == EncodingCSV {
+		header = csvHeader(cfg)
 	}
-	return multiWriteSyncer
+	return newRotatingWriter(hook, onRotate, header), nil
 }
 
 func newCore(cfg *PreSetConfig) zapcore.Core {
-	multiWriteSyncer := outputTo(cfg)
-	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),            // 编码器配置
-		zapcore.NewMultiWriteSyncer(multiWriteSyncer...), // 打印到控制台和文件
-		getLoggerLevel(DefaultCfg.LogLevel),              // 日志级别
-	)
+	return buildCore(cfg, openOutputs(cfg), cfg.Encoding)
+}
+
+// coreOutputs 是 newCore 打开的输出。SetEncoder 切换控制台格式时用它重新组合 core，不重新打开文件
+type coreOutputs struct {
+	console []zapcore.WriteSyncer
+	others  []zapcore.WriteSyncer
+	masks   []*regexp.Regexp
+	extra   []zapcore.Core // 额外文件、按字段路由、事件日志等独立编码的输出
+
+	consoleHeaders []*sync.Once // 与 console 一一对应，CSV 表头在每个控制台输出上只写一次
+}
+
+func openOutputs(cfg *PreSetConfig) *coreOutputs {
+	console, others := outputTo(cfg)
+	if cfg.AsyncBufferSize > 0 {
+		console = asyncOutputs(console, cfg.AsyncBufferSize, cfg.AsyncFullPolicy)
+		others = asyncOutputs(others, cfg.AsyncBufferSize, cfg.AsyncFullPolicy)
+	}
+	out := &coreOutputs{console: console, others: others, masks: compileMasks(cfg.MaskPatterns)}
+	for range console {
+		out.consoleHeaders = append(out.consoleHeaders, new(sync.Once))
+	}
+	out.extra = extraFileCores(cfg, out.masks)
+	if cfg.RouteKey != "" {
+		out.extra = append(out.extra, newRouteCore(cfg, out.masks))
+	}
+	if outputTargets(cfg)&OutputEventLog != 0 {
+		source := cfg.EventLogSource
+		if source == "" {
+			source = cfg.SvcName
+		}
+		if core, err := newEventLogCore(cfg, source); err == nil {
+			out.extra = append(out.extra, core)
+		} else {
+			warnf("open event log %s failed: %v", source, err)
+		}
+	}
+	return out
+}
+
+// consoleIsTerminal 判断控制台输出（stdout、stderr）是否都是终端
+func consoleIsTerminal(cfg *PreSetConfig) bool {
+	targets := outputTargets(cfg)
+	if targets&OutputStdout != 0 && !isTerminal(os.Stdout) {
+		return false
+	}
+	if (targets&OutputStderr != 0 || cfg.LogOutputTo == LogOutputFileAndStderr) && !isTerminal(os.Stderr) {
+		return false
+	}
+	return true
+}
+
+// buildCore 组合输出，控制台（stdout、stderr）使用 consoleEncoding 格式，其他输出使用 cfg.Encoding
+func buildCore(cfg *PreSetConfig, out *coreOutputs, consoleEncoding string) zapcore.Core {
+	console, others := out.console, out.others
+	consoleCfg := *cfg
+	colored := cfg.ColorLevel && consoleEncoding == EncodingConsole && (cfg.ForceColor || consoleIsTerminal(cfg))
+	split := cfg.ConsoleLineEnding != "" || colored || consoleEncoding != cfg.Encoding
+	if split {
+		if cfg.ConsoleLineEnding != "" {
+			consoleCfg.LineEnding = cfg.ConsoleLineEnding
+		}
+		consoleCfg.Encoding = consoleEncoding
+		consoleCfg.colorLevel = colored
+	}
+	consoleEncoder := newEncoder(&consoleCfg)
+	if consoleEncoding == EncodingCSV && len(out.consoleHeaders) == len(console) {
+		// 表头状态保存在 coreOutputs 中，SetEncoder 重建 core 时不会重复输出
+		header := csvHeader(&consoleCfg)
+		wrapped := make([]zapcore.WriteSyncer, len(console))
+		for i, ws := range console {
+			wrapped[i] = &csvHeaderSyncer{WriteSyncer: ws, header: header, once: out.consoleHeaders[i]}
+		}
+		console = wrapped
+	}
+	if !split {
+		// 行尾、颜色、格式相同时共用一个 core，只编码一次
+		others = append(console[:len(console):len(console)], others...)
+		console = nil
+	}
+	masks := out.masks
+	var cores []zapcore.Core
+	if len(others) > 0 || len(console) == 0 {
+		ws := maskOutput(validateOutput(zapcore.NewMultiWriteSyncer(others...), cfg.Encoding, cfg.ValidateJSON), masks)
+		cores = append(cores, zapcore.NewCore(
+			newEncoder(cfg),    // 编码器配置
+			ws,                 // 打印到控制台和文件
+			zapcore.DebugLevel, // 级别由外层 levelCore 控制
+		))
+	}
+	if len(console) > 0 {
+		ws := validateOutput(zapcore.NewMultiWriteSyncer(console...), consoleEncoding, cfg.ValidateJSON)
+		cores = append(cores, zapcore.NewCore(consoleEncoder, maskOutput(ws, masks), zapcore.DebugLevel))
+	}
+	if cfg.LogOutputTo == LogOutputFileAndStderr {
+		ws := validateOutput(consoleSyncer{os.Stderr}, consoleEncoding, cfg.ValidateJSON)
+		stderr := zapcore.NewCore(consoleEncoder, maskOutput(ws, masks), zapcore.DebugLevel)
+		cores = append(cores, newLevelCore(stderr, zapcore.ErrorLevel))
+	}
+	cores = append(cores, out.extra...)
+	if len(cores) == 1 {
+		return cores[0]
+	}
+	return zapcore.NewTee(cores...)
+}
+
+// extraFileCores 为每个额外文件构建独立编码的 core，打开失败的文件跳过
+func extraFileCores(cfg *PreSetConfig, masks []*regexp.Regexp) []zapcore.Core {
+	var cores []zapcore.Core
+	for _, sink := range cfg.ExtraFiles {
+		fileCfg := *cfg
+		fileCfg.LogFilePath = sink.Path
+		fileCfg.Encoding = sink.Encoding
+		fileCfg.FileWriter = nil
+		file, err := fileSyncer(&fileCfg)
+		if err != nil {
+			warnf("open log file %s failed, skip it: %v", sink.Path, err)
+			continue
+		}
+		ws := validateOutput(fileStatusSyncer{file, sink.Path}, sink.Encoding, cfg.ValidateJSON)
+		cores = append(cores, zapcore.NewCore(newEncoder(&fileCfg), maskOutput(ws, masks), zapcore.DebugLevel))
+	}
+	return cores
+}
+
+// warnf 将日志组件自身的问题输出到 stderr
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "prettyZap: "+format+"\n", args...)
 }
 
 func getCurrentDirectory() string {
@@ -195,57 +1068,198 @@ func getFilePath() string {
 	return logfile
 }
 
+// defaultSvcName 优先使用环境变量 SERVICE_NAME，便于容器环境统一配置，否则取可执行文件名
+func defaultSvcName() string {
+	if name := os.Getenv(EnvSvcName); name != "" {
+		return name
+	}
+	return getAppname()
+}
+
 func getAppname() string {
 	full := os.Args[0]
+	if isGoRunBinary(full) {
+		// go run 生成的临时可执行文件名没有意义，改用模块名
+		if name := moduleName(); name != "" {
+			return name
+		}
+		return DefaultSvcName
+	}
 	splits := strings.Split(full, "/")
-	if len(splits) >= 1 {
-		name := splits[len(splits)-1]
+	if name := splits[len(splits)-1]; name != "" {
 		return name
 	}
 	return DefaultSvcName
 }
 
+// isGoRunBinary 判断是否为 go run 在临时目录下构建的可执行文件，如 /tmp/go-build123/b001/exe/main
+func isGoRunBinary(name string) bool {
+	return strings.Contains(filepath.ToSlash(name), "/go-build")
+}
+
+func moduleName() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Path != "" {
+		return path.Base(info.Main.Path)
+	}
+	return ""
+}
+
+// withFields 从参数中取出 zap.Field，作为结构化字段附加到日志上，其余参数用于格式化消息
+func withFields(args []interface{}) (*zap.SugaredLogger, []interface{}) {
+	var fields, rest []interface{}
+	for _, arg := range args {
+		if f, ok := arg.(zap.Field); ok {
+			fields = append(fields, f)
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(fields) == 0 {
+		return zapLogger, args
+	}
+	return zapLogger.With(fields...), rest
+}
+
 func Debug(format interface{}, args ...interface{}) {
+	log, args := withFields(args)
 	switch templet := format.(type) {
 	case string:
-		zapLogger.Debugf(templet, args...)
+		log.Debugf(templet, args...)
 	default:
-		zapLogger.Debugf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+		log.Debugf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
 	}
 }
 
 func Info(format interface{}, args ...interface{}) {
+	log, args := withFields(args)
 	switch templet := format.(type) {
 	case string:
-		zapLogger.Infof(templet, args...)
+		log.Infof(templet, args...)
 	default:
-		zapLogger.Infof(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+		log.Infof(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
 	}
 }
 
 func Warn(format interface{}, args ...interface{}) {
+	log, args := withFields(args)
 	switch templet := format.(type) {
 	case string:
-		zapLogger.Warnf(templet, args...)
+		log.Warnf(templet, args...)
 	default:
-		zapLogger.Warnf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+		log.Warnf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
 	}
 }
 
 func Error(format interface{}, args ...interface{}) {
+	log, args := withFields(args)
 	switch templet := format.(type) {
 	case string:
-		zapLogger.Errorf(templet, args...)
+		log.Errorf(templet, args...)
 	default:
-		zapLogger.Errorf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+		log.Errorf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
 	}
 }
 
 func Panic(format interface{}, args ...interface{}) {
+	if noExitForTest {
+		defer func() { recover() }()
+	}
+	log, args := withFields(args)
+	switch templet := format.(type) {
+	case string:
+		log.Panicf(templet, args...)
+	default:
+		log.Panicf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+	}
+}
+
+func Fatal(format interface{}, args ...interface{}) {
+	log, args := withFields(args)
 	switch templet := format.(type) {
 	case string:
-		zapLogger.Panicf(templet, args...)
+		log.Fatalf(templet, args...)
 	default:
-		zapLogger.Panicf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+		log.Fatalf(fmt.Sprint(format)+strings.Repeat(" %v", len(args)), args...)
+	}
+}
+
+// Shutdown 在进程退出前调用，按配置输出日志汇总并刷新所有输出。
+// JSONArrayFile 和 CompressStream 的文件在这里写入结尾并关闭，之后的日志不再写入该文件；PIDFile 在这里删除
+func Shutdown() error {
+	if DefaultCfg.SummaryOnShutdown && rootCore != nil {
+		logSummary()
+	}
+	err := zapLogger.Sync()
+	if cerr := closeStreamFiles(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if DefaultCfg.PIDFile != "" {
+		removePIDFile(DefaultCfg.PIDFile)
+	}
+	return err
+}
+
+// DebugFields 等函数直接使用非 sugar logger，不经过 fmt 格式化和 interface{} 装箱，
+// 用于性能敏感的路径
+func DebugFields(msg string, fields ...zap.Field) {
+	plainLogger.Debug(msg, fields...)
+}
+
+func InfoFields(msg string, fields ...zap.Field) {
+	plainLogger.Info(msg, fields...)
+}
+
+func WarnFields(msg string, fields ...zap.Field) {
+	plainLogger.Warn(msg, fields...)
+}
+
+func ErrorFields(msg string, fields ...zap.Field) {
+	plainLogger.Error(msg, fields...)
+}
+
+// LogDuration 以 info 级别记录从 start 到现在的耗时，字段名为 duration，格式由 EncodeDuration 决定。
+// 常见用法：defer LogDuration(time.Now(), "query")
+func LogDuration(start time.Time, msg string, fields ...zap.Field) {
+	if ce := plainLogger.Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Write(append(fields[:len(fields):len(fields)], zap.Duration("duration", time.Since(start)))...)
+	}
+}
+
+// StackDebug、StackInfo、StackWarn 附带当前 goroutine 的调用栈记录日志，用于排查某段代码是如何被调用到的。
+// 调用栈与 error 日志的堆栈一样输出在 stacktrace 中，受 MaxStackFrames 等配置影响
+func StackDebug(msg string, fields ...zap.Field) {
+	logStack(zapcore.DebugLevel, msg, fields)
+}
+
+func StackInfo(msg string, fields ...zap.Field) {
+	logStack(zapcore.InfoLevel, msg, fields)
+}
+
+func StackWarn(msg string, fields ...zap.Field) {
+	logStack(zapcore.WarnLevel, msg, fields)
+}
+
+func logStack(lvl zapcore.Level, msg string, fields []zap.Field) {
+	if ce := plainLogger.WithOptions(zap.AddCallerSkip(1)).Check(lvl, msg); ce != nil {
+		// 跳过 logStack 和 StackInfo 等函数自身
+		ce.Stack = zap.StackSkip("", 2).String
+		ce.Write(fields...)
+	}
+}
+
+// InfoAt 以指定时间而非当前时间记录一条 info 日志，用于回放历史事件
+func InfoAt(t time.Time, msg string, fields ...zap.Field) {
+	if ce := plainLogger.Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Time = t
+		ce.Write(fields...)
+	}
+}
+
+// LogErr 以 error 级别记录 err 并原样返回，便于 return prettyZap.LogErr(err, "db insert failed")；
+// err 为 nil 时不记录
+func LogErr(err error, msg string) error {
+	if err != nil {
+		plainLogger.Error(msg, zap.Error(err))
 	}
+	return err
 }
@@ -0,0 +1,132 @@
+package prettyZap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// lockedBuffer 是可并发写入的内存输出
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Sync() error {
+	return nil
+}
+
+func (b *lockedBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogger 按 cfg 构建与 InitPrettyZap 相同结构的 logger，输出写入内存，测试结束后恢复全局状态
+func captureLogger(t testing.TB, cfg PreSetConfig) *lockedBuffer {
+	out := &lockedBuffer{}
+	useLogger(t, cfg, out)
+	return out
+}
+
+func useLogger(t testing.TB, cfg PreSetConfig, ws zapcore.WriteSyncer) {
+	core := zapcore.NewCore(newEncoder(&cfg), ws, zapcore.DebugLevel)
+	prevCfg, prevSinks, prevRoot, prev, prevPlain := DefaultCfg, sinks, rootCore, zapLogger, plainLogger
+	DefaultCfg = cfg
+	sinks = newSinkSet(core)
+	rootCore = wrapRootCore(&sinkCore{set: sinks}, &cfg)
+	setLogger(newLogger(rootCore, zap.NewAtomicLevelAt(getLoggerLevel(cfg.LogLevel)), &cfg))
+	t.Cleanup(func() {
+		DefaultCfg, sinks, rootCore, zapLogger, plainLogger = prevCfg, prevSinks, prevRoot, prev, prevPlain
+	})
+}
+
+// TestFatalFlushesBufferedOutput 在子进程中通过异步输出写文件后调用 Fatal，检查进程退出后文件中有 fatal 日志和之前的日志
+func TestFatalFlushesBufferedOutput(t *testing.T) {
+	if path := os.Getenv("PRETTYZAP_FATAL_FILE"); path != "" {
+		cfg := DefaultCfg
+		cfg.LogFilePath = path
+		cfg.LogOutputTo = LogOutputFile
+		cfg.AsyncBufferSize = 1024
+		outputs := openOutputs(&cfg)
+		setLogger(newLogger(wrapRootCore(buildCore(&cfg, outputs, cfg.Encoding), &cfg), atomicLevel, &cfg))
+		for i := 0; i < 100; i++ {
+			Info("pending line %d", i)
+		}
+		Fatal("fatal message")
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "fatal.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalFlushesBufferedOutput$")
+	cmd.Env = append(os.Environ(), "PRETTYZAP_FATAL_FILE="+path)
+	err := cmd.Run()
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 1 {
+		t.Fatalf("child exited with %v, want exit status 1", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.Contains(got, `"msg":"pending line 99"`) || !strings.Contains(got, `"msg":"fatal message"`) {
+		t.Errorf("log file is missing entries written before Fatal:\n%s", got)
+	}
+}
+
+// BenchmarkInfo 和 BenchmarkInfoFields 对比 sugar 接口与 Fields 快速路径的分配次数
+func BenchmarkInfo(b *testing.B) {
+	useLogger(b, DefaultCfg, zapcore.AddSync(ioutil.Discard))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Info("request done path=%s status=%d latency=%v", "/api/users", 200, 12.5)
+	}
+}
+
+func BenchmarkInfoFields(b *testing.B) {
+	useLogger(b, DefaultCfg, zapcore.AddSync(ioutil.Discard))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		InfoFields("request done", zap.String("path", "/api/users"), zap.Int("status", 200), zap.Float64("latency", 12.5))
+	}
+}
+
+func TestTransferCfgDefaultsSvcName(t *testing.T) {
+	prev, had := os.LookupEnv(EnvSvcName)
+	os.Setenv(EnvSvcName, "billing")
+	defer func() {
+		if had {
+			os.Setenv(EnvSvcName, prev)
+		} else {
+			os.Unsetenv(EnvSvcName)
+		}
+	}()
+
+	runCfg := DefaultCfg
+	transferCfg(&PreSetConfig{LogLevel: "info"}, &runCfg)
+	if runCfg.SvcName != "billing" {
+		t.Errorf("SvcName = %q, want SERVICE_NAME value", runCfg.SvcName)
+	}
+}
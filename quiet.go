@@ -0,0 +1,79 @@
+package prettyZap
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// quietCore 暂存 error 以下的日志，只保留最近 size 条；出现 error 及以上的日志时先按顺序写出暂存的日志，
+// 作为错误的上下文。没有错误时这些日志不会输出，适合命令行工具
+type quietCore struct {
+	zapcore.Core
+	ring *quietRing // 所有通过 With 派生的 core 共享
+}
+
+type quietRing struct {
+	mu      sync.Mutex
+	entries []quietEntry
+	next    int
+	full    bool
+}
+
+type quietEntry struct {
+	core   zapcore.Core // 写出时使用暂存时的 core，保留 With 绑定的字段
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+func newQuietCore(core zapcore.Core, size int) zapcore.Core {
+	return &quietCore{Core: core, ring: &quietRing{entries: make([]quietEntry, size)}}
+}
+
+func (c *quietCore) With(fields []zapcore.Field) zapcore.Core {
+	return &quietCore{Core: c.Core.With(fields), ring: c.ring}
+}
+
+func (c *quietCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *quietCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level < zapcore.ErrorLevel {
+		// 调用方返回后可能复用 fields，这里复制一份
+		c.ring.push(quietEntry{core: c.Core, ent: ent, fields: append([]zapcore.Field(nil), fields...)})
+		return nil
+	}
+	for _, e := range c.ring.drain() {
+		e.core.Write(e.ent, e.fields)
+	}
+	return c.Core.Write(ent, fields)
+}
+
+func (r *quietRing) push(e quietEntry) {
+	r.mu.Lock()
+	r.entries[r.next] = e
+	if r.next++; r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+	r.mu.Unlock()
+}
+
+// drain 按写入顺序取出并清空暂存的日志
+func (r *quietRing) drain() []quietEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []quietEntry
+	if r.full {
+		out = append(out, r.entries[r.next:]...)
+	}
+	out = append(out, r.entries[:r.next]...)
+	for i := range r.entries {
+		r.entries[i] = quietEntry{}
+	}
+	r.next, r.full = 0, false
+	return out
+}
@@ -0,0 +1,29 @@
+package prettyZap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuietUntilError(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.QuietUntilError = 2
+	out := captureLogger(t, cfg)
+
+	Info("step 1")
+	Info("step 2")
+	Info("step 3")
+	if got := out.String(); got != "" {
+		t.Fatalf("logs before an error should be held back, got:\n%s", got)
+	}
+	Error("failed")
+	lines := out.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want the last 2 buffered lines and the error:\n%s", len(lines), out)
+	}
+	for i, want := range []string{"step 2", "step 3", "failed"} {
+		if !strings.Contains(lines[i], `"msg":"`+want+`"`) {
+			t.Errorf("line %d = %s, want msg %q", i, lines[i], want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package prettyZap
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Token 是 Register 返回的不透明句柄，零值表示未注册
+type Token uint64
+
+// registry 保存 Register 注册的 logger，worker 等长期运行的 goroutine 注册一次后按 Token 取用
+var registry = struct {
+	sync.RWMutex
+	next uint64
+	m    map[Token]*registered
+}{m: make(map[Token]*registered)}
+
+type registered struct {
+	fields []zap.Field
+	cache  atomic.Value // cachedLogger
+}
+
+type cachedLogger struct {
+	base *zap.Logger // 构建时的全局 logger，全局 logger 替换后重新构建
+	log  *zap.SugaredLogger
+}
+
+// Register 注册一个绑定 fields 的 logger 并返回它的 Token，如 worker 启动时 Register(zap.Int("worker", id))，
+// 之后通过 Token.Logger 取用，不必每次重新 With 字段，也不必层层传递 logger。
+// 不再使用时调用 Token.Release 释放
+func Register(fields ...zap.Field) Token {
+	r := &registered{fields: append([]zap.Field(nil), fields...)}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.next++
+	t := Token(registry.next)
+	registry.m[t] = r
+	return t
+}
+
+// Logger 返回 Token 对应的 logger，级别随全局级别变化；Token 未注册或已释放时返回全局 logger
+func (t Token) Logger() *zap.SugaredLogger {
+	registry.RLock()
+	r, ok := registry.m[t]
+	registry.RUnlock()
+	base := plainLogger
+	if !ok {
+		return base.WithOptions(zap.AddCallerSkip(-1)).Sugar()
+	}
+	if c, ok := r.cache.Load().(cachedLogger); ok && c.base == base {
+		return c.log
+	}
+	log := base.WithOptions(zap.AddCallerSkip(-1)).With(r.fields...).Sugar()
+	r.cache.Store(cachedLogger{base: base, log: log})
+	return log
+}
+
+// Release 释放 Token，之后 Logger 返回全局 logger
+func (t Token) Release() {
+	registry.Lock()
+	delete(registry.m, t)
+	registry.Unlock()
+}
@@ -0,0 +1,122 @@
+package prettyZap
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RemoteDropNewest = iota // 缓冲区满时丢弃新日志
+	RemoteDropOldest        // 缓冲区满时丢弃最旧的日志
+)
+
+const (
+	remoteDialTimeout = 3 * time.Second
+	remoteMaxBackoff  = 5 * time.Second
+	remoteSyncTimeout = time.Second
+)
+
+var remoteDropped uint64
+
+// RemoteDropped 返回远程输出因缓冲区满而丢弃的日志条数
+func RemoteDropped() uint64 {
+	return atomic.LoadUint64(&remoteDropped)
+}
+
+// remoteSyncer 将日志异步发送到远程地址。写入只进入有界缓冲区，不会阻塞调用方；
+// 连接断开期间日志留在缓冲区中，重连后继续发送
+type remoteSyncer struct {
+	network string
+	addr    string
+	policy  int
+	queue   chan []byte
+	pending int64 // 已入队但尚未发送或丢弃的条数
+}
+
+// newRemoteSyncer 解析 "tcp://host:port"、"udp://host:port" 或 "host:port"（默认 tcp）
+func newRemoteSyncer(addr string, bufferSize, policy int) *remoteSyncer {
+	network := "tcp"
+	if i := strings.Index(addr, "://"); i >= 0 {
+		network, addr = addr[:i], addr[i+3:]
+	}
+	if bufferSize <= 0 {
+		bufferSize = DefaultRemoteBuffer
+	}
+	s := &remoteSyncer{
+		network: network,
+		addr:    addr,
+		policy:  policy,
+		queue:   make(chan []byte, bufferSize),
+	}
+	go s.run()
+	return s
+}
+
+func (s *remoteSyncer) Write(p []byte) (int, error) {
+	// zap 会复用 p 的底层缓冲，入队前必须拷贝
+	b := make([]byte, len(p))
+	copy(b, p)
+	atomic.AddInt64(&s.pending, 1)
+	select {
+	case s.queue <- b:
+		return len(p), nil
+	default:
+	}
+	if s.policy == RemoteDropOldest {
+		select {
+		case <-s.queue:
+			s.drop()
+		default:
+		}
+		select {
+		case s.queue <- b:
+			return len(p), nil
+		default:
+		}
+	}
+	s.drop()
+	return len(p), nil
+}
+
+func (s *remoteSyncer) drop() {
+	atomic.AddUint64(&remoteDropped, 1)
+	atomic.AddInt64(&s.pending, -1)
+}
+
+// Sync 等待缓冲区发送完毕，远端不可用时最多等待 remoteSyncTimeout
+func (s *remoteSyncer) Sync() error {
+	deadline := time.Now().Add(remoteSyncTimeout)
+	for atomic.LoadInt64(&s.pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func (s *remoteSyncer) run() {
+	var conn net.Conn
+	backoff := 100 * time.Millisecond
+	for b := range s.queue {
+		for {
+			if conn == nil {
+				c, err := net.DialTimeout(s.network, s.addr, remoteDialTimeout)
+				if err != nil {
+					time.Sleep(backoff)
+					if backoff *= 2; backoff > remoteMaxBackoff {
+						backoff = remoteMaxBackoff
+					}
+					continue
+				}
+				conn, backoff = c, 100*time.Millisecond
+			}
+			if _, err := conn.Write(b); err != nil {
+				conn.Close()
+				conn = nil
+				continue
+			}
+			break
+		}
+		atomic.AddInt64(&s.pending, -1)
+	}
+}
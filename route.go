@@ -0,0 +1,213 @@
+package prettyZap
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// routeCore 按字段 key 的值把日志写入 RouteDir 下以值命名的文件，如 tenant_id=42 写入 42.log。
+// 不带该字段的日志不会写入
+type routeCore struct {
+	r     *router
+	enc   zapcore.Encoder
+	value string // 通过 With 绑定的字段值
+}
+
+func newRouteCore(cfg *PreSetConfig, masks []*regexp.Regexp) zapcore.Core {
+	maxFiles, idle := cfg.RouteMaxFiles, time.Duration(cfg.RouteIdleSec)*time.Second
+	if maxFiles <= 0 {
+		maxFiles = DefaultRouteMaxFiles
+	}
+	if idle <= 0 {
+		idle = DefaultRouteIdleSec * time.Second
+	}
+	r := &router{cfg: *cfg, masks: masks, maxFiles: maxFiles, idle: idle, writers: make(map[string]*routeWriter)}
+	if r.cfg.RouteDir == "" {
+		r.cfg.RouteDir = filepath.Dir(cfg.LogFilePath)
+	}
+	go r.closeIdle()
+	registerCloser("", r)
+	return &routeCore{r: r, enc: newEncoder(cfg)}
+}
+
+func (c *routeCore) Enabled(zapcore.Level) bool {
+	return true // 级别由外层 levelCore 控制
+}
+
+func (c *routeCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	value := c.value
+	if v, ok := routeValue(fields, c.r.cfg.RouteKey); ok {
+		value = v
+	}
+	return &routeCore{r: c.r, enc: enc, value: value}
+}
+
+func (c *routeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *routeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	value := c.value
+	if v, ok := routeValue(fields, c.r.cfg.RouteKey); ok {
+		value = v
+	}
+	if value == "" {
+		return nil
+	}
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	return c.r.write(value, buf.Bytes())
+}
+
+func (c *routeCore) Sync() error {
+	return nil
+}
+
+func routeValue(fields []zapcore.Field, key string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != key {
+			continue
+		}
+		switch f.Type {
+		case zapcore.StringType:
+			return f.String, true
+		case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+			return strconv.FormatInt(f.Integer, 10), true
+		case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+			return strconv.FormatUint(uint64(f.Integer), 10), true
+		case zapcore.StringerType:
+			return fmt.Sprint(f.Interface), true
+		}
+	}
+	return "", false
+}
+
+// router 管理按值打开的文件，打开的文件数不超过 maxFiles，超过 idle 未写入的文件会被关闭。
+// 文件与主文件一样通过 claimFile 占用、openFileSyncer 打开，值与主文件同名时不会两个 lumberjack 轮转同一文件
+type router struct {
+	cfg      PreSetConfig
+	masks    []*regexp.Regexp
+	maxFiles int
+	idle     time.Duration
+
+	mu      sync.Mutex
+	writers map[string]*routeWriter
+}
+
+type routeWriter struct {
+	path     string
+	ws       zapcore.WriteSyncer
+	lastUsed time.Time
+}
+
+func (r *router) write(value string, p []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.writers[value]
+	if !ok {
+		if len(r.writers) >= r.maxFiles {
+			r.closeOldest()
+		}
+		var err error
+		if w, err = r.open(value); err != nil {
+			return err
+		}
+		r.writers[value] = w
+	}
+	w.lastUsed = time.Now()
+	for _, re := range r.masks {
+		p = re.ReplaceAll(p, maskReplacement)
+	}
+	_, err := w.ws.Write(p)
+	return err
+}
+
+// open 打开 value 对应的文件，轮转、压缩等配置与主文件相同
+func (r *router) open(value string) (*routeWriter, error) {
+	cfg := r.cfg
+	cfg.LogFilePath = filepath.Join(r.cfg.RouteDir, routeFileName(value))
+	cfg.FileWriter = nil
+	if err := claimFile(cfg.LogFilePath); err != nil {
+		return nil, err
+	}
+	ws, err := openFileSyncer(&cfg)
+	if err != nil {
+		releaseFile(cfg.LogFilePath)
+		return nil, err
+	}
+	return &routeWriter{path: cfg.LogFilePath, ws: ws}, nil
+}
+
+func (w *routeWriter) close() {
+	if c, ok := w.ws.(io.Closer); ok {
+		c.Close()
+	}
+	releaseFile(w.path)
+}
+
+// closeOldest 关闭最久未写入的文件，调用方持有锁
+func (r *router) closeOldest() {
+	var oldest string
+	for v, w := range r.writers {
+		if oldest == "" || w.lastUsed.Before(r.writers[oldest].lastUsed) {
+			oldest = v
+		}
+	}
+	r.writers[oldest].close()
+	delete(r.writers, oldest)
+}
+
+// Close 关闭所有打开的文件，之后的写入会重新打开文件
+func (r *router) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for v, w := range r.writers {
+		w.close()
+		delete(r.writers, v)
+	}
+	return nil
+}
+
+func (r *router) closeIdle() {
+	for range time.Tick(r.idle / 2) {
+		r.mu.Lock()
+		for v, w := range r.writers {
+			if time.Since(w.lastUsed) > r.idle {
+				w.close()
+				delete(r.writers, v)
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+// routeFileName 将字段值转为安全的文件名，只保留字母、数字、'-'、'_' 和 '.'
+func routeFileName(value string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, value)
+	if strings.Trim(name, ".") == "" {
+		name = "_" + name // 避免 "." 和 ".."
+	}
+	return name + ".log"
+}
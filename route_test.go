@@ -0,0 +1,39 @@
+package prettyZap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRouteCore(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultCfg
+	cfg.LogFilePath = filepath.Join(dir, "app.log")
+	cfg.RouteKey = "tenant"
+	if err := claimFile(cfg.LogFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer releaseFile(cfg.LogFilePath)
+	core := newRouteCore(&cfg, nil)
+	defer core.(*routeCore).r.Close()
+
+	// RouteDir 为空时写入主文件所在目录
+	if err := core.Write(zapcore.Entry{Message: "hello"}, []zapcore.Field{zap.String("tenant", "42")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "42.log")); err != nil {
+		t.Errorf("route file not created in the log directory: %v", err)
+	}
+	// 与主文件同名的值不能再打开一个 lumberjack
+	if err := core.Write(zapcore.Entry{Message: "hello"}, []zapcore.Field{zap.String("tenant", "app")}); err == nil {
+		t.Error("routing into the main log file should fail")
+	}
+	// 没有该字段的日志不写入
+	if err := core.Write(zapcore.Entry{Message: "hello"}, nil); err != nil {
+		t.Error(err)
+	}
+}
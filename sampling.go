@@ -0,0 +1,87 @@
+package prettyZap
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newSamplingCore 只对不高于 SampleMaxLevel（默认 info）的日志采样，
+// 更高级别的日志总是写出，避免事故期间错误日志被采样丢弃。
+// level 调到 debug 时不采样，排查问题时打开的 debug 日志不会被丢弃。
+// InitPrettyZap 后 SampleWarmupSec 秒内同样不采样，保留初始化阶段的完整日志
+func newSamplingCore(core zapcore.Core, level zapcore.LevelEnabler, cfg *PreSetConfig) zapcore.Core {
+	maxLevel := getLoggerLevel(cfg.SampleMaxLevel)
+	sampled := newLevelCore(core, zap.LevelEnablerFunc(func(l zapcore.Level) bool { return l <= maxLevel }))
+	passed := newLevelCore(core, zap.LevelEnablerFunc(func(l zapcore.Level) bool { return l > maxLevel }))
+	sampler := zapcore.NewSamplerWithOptions(sampled, time.Second, cfg.SampleInitial, cfg.SampleThereafter)
+	if len(cfg.SampleFields) > 0 {
+		// zap 的采样在 Check 时进行，看不到字段，这里改为在 Write 时按消息和字段值计数
+		sampler = &filterCore{Core: sampled, keep: sampleByFields(cfg.SampleFields, cfg.SampleInitial, cfg.SampleThereafter)}
+	}
+	// Named、NewLogger 随时可能创建新的采样 core，预热期统一从 InitPrettyZap 起算
+	warmup, warmupEnd := cfg.SampleWarmupSec > 0, initTime.Add(time.Duration(cfg.SampleWarmupSec)*time.Second)
+	return zapcore.NewTee(
+		&samplingSwitch{
+			Core:    sampled,
+			sampler: sampler,
+			bypass: func() bool {
+				return level.Enabled(zapcore.DebugLevel) || (warmup && time.Now().Before(warmupEnd))
+			},
+		},
+		passed,
+	)
+}
+
+// samplingSwitch 在 bypass 返回 true 时跳过采样，直接交给内层 core
+type samplingSwitch struct {
+	zapcore.Core
+	sampler zapcore.Core
+	bypass  func() bool
+}
+
+func (s *samplingSwitch) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingSwitch{Core: s.Core.With(fields), sampler: s.sampler.With(fields), bypass: s.bypass}
+}
+
+func (s *samplingSwitch) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.bypass() {
+		return s.Core.Check(ent, ce)
+	}
+	return s.sampler.Check(ent, ce)
+}
+
+// sampleByFields 与 zap 的采样规则相同，但计数的 key 是级别、消息加上 keys 中各字段的值（包括 With 绑定的字段），
+// 如按 tenant_id 区分后，一个租户的大量日志不会挤掉其他租户的同名日志
+func sampleByFields(keys []string, first, thereafter int) func(zapcore.Entry, []zapcore.Field) bool {
+	var (
+		mu     sync.Mutex
+		window int64
+		counts = make(map[string]int)
+	)
+	return func(ent zapcore.Entry, fields []zapcore.Field) bool {
+		var b strings.Builder
+		b.WriteString(ent.Level.String())
+		b.WriteByte(0)
+		b.WriteString(ent.Message)
+		for _, k := range keys {
+			v, _ := routeValue(fields, k)
+			b.WriteByte(0)
+			b.WriteString(v)
+		}
+		key := b.String()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if sec := ent.Time.Unix(); sec > window {
+			window = sec
+			counts = make(map[string]int)
+		}
+		counts[key]++
+		n := counts[key]
+		return n <= first || (thereafter > 0 && (n-first)%thereafter == 0)
+	}
+}
@@ -0,0 +1,33 @@
+package prettyZap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampleWarmupCountsFromInit(t *testing.T) {
+	prev := initTime
+	defer func() { initTime = prev }()
+	cfg := DefaultCfg
+	cfg.SampleInitial = 1
+	cfg.SampleWarmupSec = 60
+
+	for _, tt := range []struct {
+		name      string
+		startedAt time.Time
+		want      int
+	}{
+		{"during warm-up", time.Now(), 5},
+		// 启动一小时后创建的 logger 不再有预热期
+		{"after warm-up", time.Now().Add(-time.Hour), 1},
+	} {
+		initTime = tt.startedAt
+		out := captureLogger(t, cfg)
+		for i := 0; i < 5; i++ {
+			Info("same message")
+		}
+		if got := len(out.Lines()); got != tt.want {
+			t.Errorf("%s: got %d lines, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package prettyZap
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 换行替换为字面量 \n，日志采集按行切分时每条日志只占一行
+var newlineEscaper = strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+
+func escapeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	return newlineEscaper.Replace(s)
+}
+
+// singleLineCore 转义消息中的换行。console 的字段按 JSON 编码，堆栈改为普通字段即可由编码器转义；
+// CSV 原样输出，堆栈和字段都需要转义。JSON 和 logfmt 本身会转义换行，不需要它
+type singleLineCore struct {
+	zapcore.Core
+	stackKey string
+	fields   bool // 是否同时转义字符串字段，CSV 原样输出字段值
+}
+
+func newSingleLineCore(core zapcore.Core, cfg *PreSetConfig) zapcore.Core {
+	switch cfg.Encoding {
+	case EncodingConsole, EncodingCSV:
+	default:
+		return core
+	}
+	return &singleLineCore{Core: core, stackKey: newEncoderConfig(cfg).StacktraceKey, fields: cfg.Encoding == EncodingCSV}
+}
+
+func (c *singleLineCore) With(fields []zapcore.Field) zapcore.Core {
+	return &singleLineCore{Core: c.Core.With(c.escape(fields)), stackKey: c.stackKey, fields: c.fields}
+}
+
+func (c *singleLineCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *singleLineCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = escapeNewlines(ent.Message)
+	fields = c.escape(fields)
+	switch {
+	case c.fields:
+		ent.Stack = escapeNewlines(ent.Stack)
+	case ent.Stack != "" && c.stackKey != "":
+		fields = append(fields[:len(fields):len(fields)], zap.String(c.stackKey, ent.Stack))
+		ent.Stack = ""
+	}
+	return c.Core.Write(ent, fields)
+}
+
+func (c *singleLineCore) escape(fields []zapcore.Field) []zapcore.Field {
+	if !c.fields || len(fields) == 0 {
+		return fields
+	}
+	out := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, singleLineField(f))
+	}
+	return out
+}
+
+func singleLineField(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = escapeNewlines(f.String)
+	case zapcore.ByteStringType:
+		return zap.String(f.Key, escapeNewlines(string(f.Interface.([]byte))))
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return zap.String(f.Key, escapeNewlines(s.String()))
+		}
+	case zapcore.ErrorType:
+		// 转为字符串，同时去掉多行的 errorVerbose
+		if err, ok := f.Interface.(error); ok {
+			return zap.String(f.Key, escapeNewlines(err.Error()))
+		}
+	}
+	return f
+}
@@ -0,0 +1,310 @@
+package prettyZap
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// activeFiles 记录已被 logger 使用的日志文件，多个 lumberjack 实例轮转同一文件会互相破坏
+var activeFiles = struct {
+	sync.Mutex
+	m map[string]struct{}
+}{m: make(map[string]struct{})}
+
+// rotators 按文件绝对路径记录 lumberjack 输出，供运行时调整轮转参数
+var rotators = struct {
+	sync.Mutex
+	m map[string]*rotatingWriter
+}{m: make(map[string]*rotatingWriter)}
+
+// RotationConfig 是可在运行时调整的轮转参数，含义同 PreSetConfig 中的同名字段
+type RotationConfig struct {
+	MaxLogSizeMb int
+	MaxBackup    int
+	MaxAgeDay    int
+}
+
+// UpdateRotation 调整 InitPrettyZap 所用日志文件的轮转参数，下次轮转时生效
+func UpdateRotation(cfg RotationConfig) error {
+	rotators.Lock()
+	w, ok := rotators.m[absPath(DefaultCfg.LogFilePath)]
+	rotators.Unlock()
+	if !ok {
+		return errors.New("log file rotation is not active")
+	}
+	w.update(cfg)
+	DefaultCfg.MaxLogSizeMb = cfg.MaxLogSizeMb
+	DefaultCfg.MaxBackup = cfg.MaxBackup
+	DefaultCfg.MaxAgeDay = cfg.MaxAgeDay
+	return nil
+}
+
+// rotatingWriter 串行化对 lumberjack 的写入，使轮转参数可以在写入间隙安全修改
+type rotatingWriter struct {
+	mu       sync.Mutex
+	lj       *lumberjack.Logger
+	closed   bool
+	onRotate func(string)
+	header   []byte // 非空时写在每个新文件的开头，如 CSV 表头
+	size     int64  // 当前文件大小，-1 表示尚未打开，只在 onRotate 或 header 非空时维护
+}
+
+func newRotatingWriter(lj *lumberjack.Logger, onRotate func(string), header []byte) *rotatingWriter {
+	w := &rotatingWriter{lj: lj, onRotate: onRotate, header: header, size: -1}
+	rotators.Lock()
+	rotators.m[absPath(lj.Filename)] = w
+	rotators.Unlock()
+	return w
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		// lumberjack 关闭后再写会重新打开文件
+		return 0, os.ErrClosed
+	}
+	if w.onRotate == nil && len(w.header) == 0 {
+		return w.lj.Write(p)
+	}
+	// lumberjack 不通知轮转，按它的规则根据文件大小判断这次写入是否会触发轮转
+	opening := false // 首次写入已有文件
+	if w.size < 0 {
+		w.size = 0
+		if info, err := os.Stat(w.lj.Filename); err == nil {
+			w.size, opening = info.Size(), true
+		}
+	}
+	data := p
+	if len(w.header) > 0 && (w.size == 0 || w.willRotate(opening, int64(len(p)))) {
+		// 与日志一起写入，轮转时表头落在新文件的开头
+		data = append(w.header[:len(w.header):len(w.header)], p...)
+	}
+	rotate := w.willRotate(opening, int64(len(data)))
+	n, err := w.lj.Write(data)
+	if err != nil {
+		return 0, err
+	}
+	if rotate {
+		w.size = int64(n)
+		if w.onRotate != nil {
+			if name := lastBackup(w.lj.Filename); name != "" {
+				go w.onRotate(name)
+			}
+		}
+	} else {
+		w.size += int64(n)
+	}
+	return len(p), nil
+}
+
+// willRotate 判断写入 n 字节是否会触发轮转；首次写入时 lumberjack 打开已有文件，放不下时直接轮转
+func (w *rotatingWriter) willRotate(opening bool, n int64) bool {
+	max := int64(w.lj.MaxSize) * 1024 * 1024
+	if max == 0 {
+		max = 100 * 1024 * 1024 // lumberjack 的默认值
+	}
+	if opening {
+		return w.size+n >= max
+	}
+	return w.size+n > max
+}
+
+// backupTimeFormat 是 lumberjack 备份文件名中的时间戳格式
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// lastBackup 返回 filename 最新的备份文件。备份名为 name-时间戳.ext，时间戳可按字典序比较；
+// 开启压缩时可能已被替换为 .gz 文件
+func lastBackup(filename string) string {
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filename, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return ""
+	}
+	var backups []string
+	for _, m := range matches {
+		if isBackupName(filename, m) {
+			backups = append(backups, m)
+		}
+	}
+	if len(backups) == 0 {
+		return ""
+	}
+	sort.Strings(backups)
+	return backups[len(backups)-1]
+}
+
+// isBackupName 判断 path 是否为 filename 轮转出的备份。只比较前后缀会把同目录下的 app-json.log
+// 误认为 app.log 的备份，这里要求中间部分是 lumberjack 的时间戳
+func isBackupName(filename, path string) bool {
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filepath.Base(filename), ext) + "-"
+	name := strings.TrimSuffix(filepath.Base(path), ".gz")
+	if len(name) < len(prefix)+len(ext) || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return false
+	}
+	_, err := time.Parse(backupTimeFormat, name[len(prefix):len(name)-len(ext)])
+	return err == nil
+}
+
+func (w *rotatingWriter) Sync() error {
+	return nil
+}
+
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	path := absPath(w.lj.Filename)
+	rotators.Lock()
+	if rotators.m[path] == w {
+		delete(rotators.m, path)
+	}
+	rotators.Unlock()
+	return w.lj.Close()
+}
+
+func (w *rotatingWriter) update(cfg RotationConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lj.MaxSize = cfg.MaxLogSizeMb
+	w.lj.MaxBackups = cfg.MaxBackup
+	w.lj.MaxAge = cfg.MaxAgeDay
+}
+
+func claimFile(path string) error {
+	path = absPath(path)
+	activeFiles.Lock()
+	defer activeFiles.Unlock()
+	if _, ok := activeFiles.m[path]; ok {
+		return fmt.Errorf("log file %s is already used by another logger", path)
+	}
+	activeFiles.m[path] = struct{}{}
+	return nil
+}
+
+func releaseFile(path string) {
+	path = absPath(path)
+	activeFiles.Lock()
+	delete(activeFiles.m, path)
+	activeFiles.Unlock()
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+const (
+	openBackoff    = 100 * time.Millisecond
+	openMaxBackoff = 5 * time.Second
+)
+
+// openWithRetry 以追加模式打开日志文件，失败时最多重试 retries 次
+func openWithRetry(path string, retries int) (*os.File, error) {
+	backoff := openBackoff
+	for i := 0; ; i++ {
+		f, err := openAppend(path)
+		if err == nil || i >= retries {
+			return f, err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > openMaxBackoff {
+			backoff = openMaxBackoff
+		}
+	}
+}
+
+func openAppend(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// gzipSyncer 将日志以 gzip 流写入文件，Sync 时刷新压缩缓冲，保证已写出的内容可被解压读取
+type gzipSyncer struct {
+	mu     sync.Mutex
+	gz     *gzip.Writer
+	f      *os.File
+	done   chan struct{}
+	closed bool
+}
+
+func newGzipSyncer(f *os.File, flushInterval time.Duration) *gzipSyncer {
+	s := &gzipSyncer{gz: gzip.NewWriter(f), f: f, done: make(chan struct{})}
+	if flushInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(flushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.Sync()
+				case <-s.done:
+					return
+				}
+			}
+		}()
+	}
+	return s
+}
+
+func (s *gzipSyncer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	return s.gz.Write(p)
+}
+
+func (s *gzipSyncer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	if err := s.gz.Flush(); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close 写入 gzip 结尾并关闭文件，重复调用直接返回
+func (s *gzipSyncer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	if err := s.gz.Close(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// consoleSyncer 包装 stdout、stderr。对终端或管道调用 fsync 会返回 EINVAL 等错误而且没有意义，
+// 这里忽略 Sync，避免 Shutdown、Drain 总是返回错误
+type consoleSyncer struct {
+	*os.File
+}
+
+func (consoleSyncer) Sync() error {
+	return nil
+}
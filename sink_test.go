@@ -0,0 +1,85 @@
+package prettyZap
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLastBackupIgnoresOtherSinks(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app.log",
+		"app-2020-01-01T00-00-00.000.log",
+		"app-2020-01-02T00-00-00.000.log.gz",
+		"app-json.log",
+		"app-json-2020-03-01T00-00-00.000.log",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := lastBackup(filepath.Join(dir, "app.log"))
+	if want := filepath.Join(dir, "app-2020-01-02T00-00-00.000.log.gz"); got != want {
+		t.Errorf("lastBackup = %s, want %s", got, want)
+	}
+}
+
+func TestIsBackupName(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/logs/app-2020-01-01T00-00-00.000.log", true},
+		{"/logs/app-2020-01-01T00-00-00.000.log.gz", true},
+		{"/logs/app.log", false},
+		{"/logs/app-json.log", false},
+		{"/logs/app-json-2020-01-01T00-00-00.000.log", false},
+		{"/logs/app-2020-01-01T00-00-00.000.txt", false},
+	}
+	for _, tt := range tests {
+		if got := isBackupName("/logs/app.log", tt.path); got != tt.want {
+			t.Errorf("isBackupName(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestShutdownClosesGzipStream(t *testing.T) {
+	cfg := DefaultCfg
+	cfg.LogFilePath = filepath.Join(t.TempDir(), "app.log.gz")
+	cfg.CompressStream = true
+	cfg.Encoding = EncodingCSV
+	ws, err := fileSyncer(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useLogger(t, cfg, ws)
+
+	Info("before shutdown")
+	if err := Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后的写入不能 panic
+	Info("after shutdown")
+
+	f, err := os.Open(cfg.LogFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 缺少 gzip 结尾时 ReadAll 返回 unexpected EOF
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip stream is incomplete: %v", err)
+	}
+	if !strings.Contains(string(data), "before shutdown") {
+		t.Errorf("log line missing from gzip stream: %q", data)
+	}
+}
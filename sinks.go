@@ -0,0 +1,171 @@
+package prettyZap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// sinks 是 InitPrettyZap 构建的可变输出集合，AddSink/RemoveSink 在其上增删输出
+var sinks *sinkSet
+
+// sinkSet 保存基础输出和运行时添加的输出，每次变更后重新组合 tee 并递增 gen
+type sinkSet struct {
+	mu    sync.RWMutex
+	gen   uint64
+	tee   zapcore.Core
+	base  zapcore.Core
+	extra map[string]zapcore.Core
+
+	outputs  *coreOutputs // base 使用的输出，SetEncoder 据此重建 base；nil 表示不支持
+	disabled bool         // Disable 期间 tee 为空 core，base、extra 保留，Enable 时恢复
+}
+
+func newSinkSet(base zapcore.Core) *sinkSet {
+	return &sinkSet{tee: base, base: base, extra: make(map[string]zapcore.Core)}
+}
+
+func (s *sinkSet) rebuild() {
+	s.gen++
+	if s.disabled {
+		s.tee = zapcore.NewNopCore()
+		return
+	}
+	cores := []zapcore.Core{s.base}
+	for _, c := range s.extra {
+		cores = append(cores, c)
+	}
+	s.tee = zapcore.NewTee(cores...)
+}
+
+func (s *sinkSet) setDisabled(disabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabled = disabled
+	s.rebuild()
+}
+
+func (s *sinkSet) current() (uint64, zapcore.Core) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.gen, s.tee
+}
+
+// AddSink 在运行时增加一个输出，使用与其他输出相同的编码配置，name 用于之后移除。
+// 例如排查问题时临时写文件：AddSink("debug", zapcore.AddSync(f))
+func AddSink(name string, ws zapcore.WriteSyncer) error {
+	if sinks == nil {
+		return errors.New("prettyZap is not initialized")
+	}
+	return sinks.add(name, zapcore.NewCore(newEncoder(&DefaultCfg), ws, zapcore.DebugLevel))
+}
+
+func (s *sinkSet) add(name string, core zapcore.Core) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.extra[name]; ok {
+		return fmt.Errorf("sink %q already exists", name)
+	}
+	s.extra[name] = core
+	s.rebuild()
+	return nil
+}
+
+// RemoveSink 移除 AddSink 添加的输出，移除前会 Sync；输出本身由调用方关闭
+func RemoveSink(name string) error {
+	if sinks == nil {
+		return errors.New("prettyZap is not initialized")
+	}
+	return sinks.remove(name)
+}
+
+func (s *sinkSet) remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	core, ok := s.extra[name]
+	if !ok {
+		return fmt.Errorf("sink %q not found", name)
+	}
+	delete(s.extra, name)
+	s.rebuild()
+	return core.Sync()
+}
+
+// SetEncoder 在运行时切换 stdout、stderr 输出的格式，如排查问题时改为 EncodingConsole 便于阅读，
+// 文件等其他输出不受影响；format 为空时恢复为 Encoding。已通过 With 绑定的字段会按新格式重新编码
+func SetEncoder(format string) error {
+	switch format {
+	case "":
+		format = DefaultCfg.Encoding
+	case EncodingJSON, EncodingConsole, EncodingLogfmt, EncodingCSV:
+	default:
+		return fmt.Errorf("unknown encoding %q", format)
+	}
+	if format == EncodingJSON && DefaultCfg.Encoding == "" {
+		// 与默认的空值等价，控制台仍与文件共用一个 core
+		format = ""
+	}
+	if sinks == nil {
+		return errors.New("prettyZap is not initialized")
+	}
+	sinks.mu.Lock()
+	defer sinks.mu.Unlock()
+	if sinks.outputs == nil {
+		return errors.New("outputs are closed")
+	}
+	sinks.base = buildCore(&DefaultCfg, sinks.outputs, format)
+	sinks.rebuild()
+	return nil
+}
+
+// sinkCore 将写入转发到 sinkSet 当前的 tee。With 绑定的字段在输出变更后重新应用，
+// 结果按 gen 缓存，输出不变时不会重复编码字段
+type sinkCore struct {
+	set    *sinkSet
+	fields []zapcore.Field
+	cache  atomic.Value // cachedSinks
+}
+
+type cachedSinks struct {
+	gen  uint64
+	core zapcore.Core
+}
+
+func (c *sinkCore) current() zapcore.Core {
+	gen, tee := c.set.current()
+	if cached, ok := c.cache.Load().(cachedSinks); ok && cached.gen == gen {
+		return cached.core
+	}
+	core := tee
+	if len(c.fields) > 0 {
+		core = tee.With(c.fields)
+	}
+	c.cache.Store(cachedSinks{gen: gen, core: core})
+	return core
+}
+
+func (c *sinkCore) Enabled(lvl zapcore.Level) bool {
+	return c.current().Enabled(lvl)
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sinkCore{set: c.set, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+func (c *sinkCore) Sync() error {
+	return c.current().Sync()
+}
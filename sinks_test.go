@@ -0,0 +1,65 @@
+package prettyZap
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAddRemoveSink(t *testing.T) {
+	out := captureLogger(t, DefaultCfg)
+	extra := &lockedBuffer{}
+
+	log := plainLogger.Sugar().With("user", "u1")
+	if err := AddSink("debug", extra); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSink("debug", extra); err == nil {
+		t.Error("adding a sink twice should fail")
+	}
+	// 添加输出前派生的 logger 同样写入新输出，With 绑定的字段也要保留
+	log.Infow("while added")
+	if err := RemoveSink("debug"); err != nil {
+		t.Fatal(err)
+	}
+	log.Infow("after remove")
+
+	if got := extra.String(); !strings.Contains(got, `"msg":"while added"`) || !strings.Contains(got, `"user":"u1"`) ||
+		strings.Contains(got, "after remove") {
+		t.Errorf("extra sink got:\n%s", got)
+	}
+	if lines := out.Lines(); len(lines) != 2 {
+		t.Errorf("base sink got %d lines, want 2:\n%s", len(lines), out)
+	}
+}
+
+func TestSetEncoder(t *testing.T) {
+	captureLogger(t, DefaultCfg)
+	console := &lockedBuffer{}
+	sinks.outputs = &coreOutputs{console: []zapcore.WriteSyncer{console}}
+	log := plainLogger.Sugar().With("user", "u1")
+
+	if err := SetEncoder("yaml"); err == nil {
+		t.Error("unknown encoding should be rejected")
+	}
+	if err := SetEncoder(EncodingConsole); err != nil {
+		t.Fatal(err)
+	}
+	log.Infow("console")
+	if err := SetEncoder(""); err != nil {
+		t.Fatal(err)
+	}
+	log.Infow("json")
+
+	lines := console.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), console)
+	}
+	if strings.HasPrefix(lines[0], "{") || !strings.Contains(lines[0], `"user": "u1"`) {
+		t.Errorf("want console format with bound fields, got %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "{") || !strings.Contains(lines[1], `"user":"u1"`) {
+		t.Errorf("want JSON after reset, got %s", lines[1])
+	}
+}
@@ -0,0 +1,41 @@
+package prettyZap
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const statsdFlushInterval = time.Second
+
+// startStatsd 每秒将各级别新增的日志条数以 log.<level> 计数器发送到 statsd。
+// 写日志只增加计数，不会被 statsd 阻塞；UDP 发送失败时丢弃
+func startStatsd(addr string) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		var sent [len(levelCounts)]uint64
+		var buf bytes.Buffer
+		for range time.Tick(statsdFlushInterval) {
+			buf.Reset()
+			for i := range levelCounts {
+				n := atomic.LoadUint64(&levelCounts[i])
+				if n == sent[i] {
+					continue
+				}
+				fmt.Fprintf(&buf, "log.%s:%d|c\n", zapcore.DebugLevel+zapcore.Level(i), n-sent[i])
+				sent[i] = n
+			}
+			if buf.Len() > 0 {
+				conn.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,30 @@
+package prettyZap
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelCounts 按级别记录写出的日志条数，下标为 level - DebugLevel
+var levelCounts [zapcore.FatalLevel - zapcore.DebugLevel + 1]uint64
+
+func countHook(ent zapcore.Entry) error {
+	if ent.Level >= zapcore.DebugLevel && ent.Level <= zapcore.FatalLevel {
+		atomic.AddUint64(&levelCounts[ent.Level-zapcore.DebugLevel], 1)
+	}
+	return nil
+}
+
+// logSummary 输出各级别的日志条数。使用固定的 info 级别，全局级别调高后也能看到汇总
+func logSummary() {
+	counts := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		for i := range levelCounts {
+			enc.AddUint64((zapcore.DebugLevel + zapcore.Level(i)).String(), atomic.LoadUint64(&levelCounts[i]))
+		}
+		return nil
+	})
+	log := newLogger(rootCore, zapcore.InfoLevel, &DefaultCfg).WithOptions(zap.AddCallerSkip(-1))
+	log.Info("log summary", zap.Object("counts", counts))
+}
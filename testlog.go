@@ -0,0 +1,56 @@
+package prettyZap
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// InitObserved 将全局日志替换为 observer core，返回记录到的日志，供测试断言使用。
+// 不启动 HTTP 服务，所有级别都会被记录。
+func InitObserved() *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	setLogger(zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.Fields(globalFields(&DefaultCfg)...)))
+	return logs
+}
+
+// InitForTest 将全局日志输出到 t.Log，日志归属到对应测试，只在测试失败或 -v 时显示。
+// 不启动 HTTP 服务，测试结束后恢复原来的 logger
+func InitForTest(t testing.TB) {
+	prev, prevPlain := zapLogger, plainLogger
+	setLogger(zaptest.NewLogger(t,
+		zaptest.Level(zapcore.DebugLevel),
+		zaptest.WrapOptions(
+			zap.AddCaller(),
+			zap.AddCallerSkip(1),
+			zap.Fields(globalFields(&DefaultCfg)...))))
+	t.Cleanup(func() {
+		zapLogger, plainLogger = prev, prevPlain
+	})
+}
+
+// noExitForTest 为 true 时包级 Panic 不再 panic
+var noExitForTest bool
+
+// NoExitForTest 使测试中的 Panic、Fatal 只记录日志后返回，不 panic 也不退出进程，
+// 便于断言它们被调用。需在 InitObserved 或 InitForTest 之后调用，测试结束后恢复
+func NoExitForTest(t testing.TB) {
+	prev, prevPlain := zapLogger, plainLogger
+	setLogger(plainLogger.WithOptions(zap.WithFatalHook(noExit{})))
+	noExitForTest = true
+	t.Cleanup(func() {
+		zapLogger, plainLogger = prev, prevPlain
+		noExitForTest = false
+	})
+}
+
+// noExit 在 fatal 日志写出后什么也不做。zap 会把 WriteThenNoop 替换为退出，所以需要自定义类型
+type noExit struct{}
+
+func (noExit) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {}
@@ -0,0 +1,111 @@
+package prettyZap
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// throttles 是 Throttle 注册的规则，写时复制，日志路径上只需原子读取
+var throttles atomic.Value // []*throttleRule
+
+var throttleMu sync.Mutex
+
+type throttleRule struct {
+	template   string
+	re         *regexp.Regexp
+	interval   time.Duration
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+}
+
+// Throttle 限制消息匹配 template 的日志在 interval 内最多输出一条，期间被抑制的条数在下一条输出时
+// 以 suppressed 字段附带。template 中的格式化动词（如 %v、%d）匹配任意内容，
+// 因此 Errorf("db timeout: %v", err) 可以用 Throttle("db timeout: %v", time.Minute) 限制。
+// 对同一 template 再次调用会替换原规则，interval 不大于 0 时取消限制
+func Throttle(template string, interval time.Duration) {
+	throttleMu.Lock()
+	defer throttleMu.Unlock()
+	old, _ := throttles.Load().([]*throttleRule)
+	rules := make([]*throttleRule, 0, len(old)+1)
+	for _, r := range old {
+		if r.template != template {
+			rules = append(rules, r)
+		}
+	}
+	if interval > 0 {
+		rules = append(rules, &throttleRule{template: template, re: templateRegexp(template), interval: interval})
+	}
+	throttles.Store(rules)
+}
+
+// templateRegexp 将 fmt 格式串转为匹配格式化结果的正则，%% 匹配 %，其他动词匹配任意内容
+func templateRegexp(template string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '%' {
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			continue
+		}
+		// 跳过标志、宽度、精度和参数索引，直到动词字母
+		j := i + 1
+		for j < len(template) && strings.IndexByte("+-# 0123456789.*[]", template[j]) >= 0 {
+			j++
+		}
+		if j < len(template) && template[j] == '%' {
+			b.WriteString("%")
+		} else {
+			b.WriteString(".*?")
+		}
+		i = j
+	}
+	b.WriteString(")$")
+	return regexp.MustCompile(b.String())
+}
+
+// throttleCore 按 Throttle 注册的规则抑制日志，没有规则时直接写出
+type throttleCore struct {
+	zapcore.Core
+}
+
+func (c *throttleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &throttleCore{Core: c.Core.With(fields)}
+}
+
+func (c *throttleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *throttleCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	rules, _ := throttles.Load().([]*throttleRule)
+	for _, r := range rules {
+		if !r.re.MatchString(ent.Message) {
+			continue
+		}
+		r.mu.Lock()
+		if !r.last.IsZero() && ent.Time.Sub(r.last) < r.interval {
+			r.suppressed++
+			r.mu.Unlock()
+			return nil
+		}
+		suppressed := r.suppressed
+		r.last, r.suppressed = ent.Time, 0
+		r.mu.Unlock()
+		if suppressed > 0 {
+			fields = append(fields[:len(fields):len(fields)], zap.Int("suppressed", suppressed))
+		}
+		break
+	}
+	return c.Core.Write(ent, fields)
+}
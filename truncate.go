@@ -0,0 +1,55 @@
+package prettyZap
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const truncatedSuffix = "..."
+
+// newTruncateCore 将消息和字符串类字段截断到 max 字节并追加 "..."
+func newTruncateCore(core zapcore.Core, max int) zapcore.Core {
+	return &rewriteCore{
+		Core: core,
+		msg:  func(s string) string { return truncate(s, max) },
+		field: func(f zapcore.Field, out []zapcore.Field) []zapcore.Field {
+			return append(out, truncateField(f, max))
+		},
+	}
+}
+
+func truncateField(f zapcore.Field, max int) zapcore.Field {
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = truncate(f.String, max)
+	case zapcore.ByteStringType:
+		if b := f.Interface.([]byte); len(b) > max {
+			return zap.String(f.Key, truncate(string(b), max))
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return zap.String(f.Key, truncate(s.String(), max))
+		}
+	case zapcore.ErrorType:
+		// 只有超长时才转为字符串，保留正常错误的 errorVerbose 等信息
+		if err, ok := f.Interface.(error); ok && len(err.Error()) > max {
+			return zap.String(f.Key, truncate(err.Error(), max))
+		}
+	}
+	return f
+}
+
+// truncate 按字节截断，不会切断 UTF-8 字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	n := max
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + truncatedSuffix
+}
@@ -0,0 +1,57 @@
+package prettyZap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	ValidateJSONOff   = iota // 不检查（默认）
+	ValidateJSONWarn         // 输出不是合法 JSON 时向 stderr 警告，日志照常写出
+	ValidateJSONPanic        // 输出不是合法 JSON 时 panic，用于测试中尽早暴露问题
+)
+
+// validateOutput 在 mode 开启且 encoding 为 JSON 时检查写出的每一行是否为合法 JSON，
+// 用于调试自定义编码器、Preset 和掩码规则，会额外解析每条日志，不建议在生产环境开启
+func validateOutput(ws zapcore.WriteSyncer, encoding string, mode int) zapcore.WriteSyncer {
+	if mode == ValidateJSONOff || (encoding != "" && encoding != EncodingJSON) {
+		return ws
+	}
+	return jsonValidator{WriteSyncer: ws, panic: mode == ValidateJSONPanic}
+}
+
+type jsonValidator struct {
+	zapcore.WriteSyncer
+	panic bool
+}
+
+func (v jsonValidator) Write(p []byte) (int, error) {
+	// JSON 编码器会转义字符串中的换行，按行切分不会拆开一条日志
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(line, &obj); err != nil {
+			msg := fmt.Sprintf("invalid JSON log line: %v: %s", err, truncateLine(line))
+			if v.panic {
+				panic("prettyZap: " + msg)
+			}
+			warnf("%s", msg)
+		}
+	}
+	return v.WriteSyncer.Write(p)
+}
+
+// truncateLine 截断警告中引用的日志内容，避免一条超长日志刷屏
+func truncateLine(line []byte) string {
+	const max = 200
+	if len(line) > max {
+		return string(line[:max]) + "..."
+	}
+	return string(line)
+}